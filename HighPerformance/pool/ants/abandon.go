@@ -0,0 +1,46 @@
+package ants
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 设置了MaxTaskDuration的时候任务的状态，任务结束和定时器超时只有一方能把状态从workerTaskRunning改掉
+const (
+	workerTaskRunning int32 = iota
+	workerTaskDone
+	workerTaskAbandoned
+)
+
+// watchTask 在worker开始执行任务之前调用，任务超过d还没有结束的时候放弃这个worker，
+// 返回任务的状态和定时器，任务结束之后需要停止定时器并把状态改成workerTaskDone
+func (p *Pool) watchTask(d time.Duration) (*int32, *time.Timer) {
+	state := new(int32)
+	timer := time.AfterFunc(d, func() {
+		if atomic.CompareAndSwapInt32(state, workerTaskRunning, workerTaskAbandoned) {
+			p.abandonWorker(d)
+		}
+	})
+	return state, timer
+}
+
+// abandonWorker 把卡住的worker从pool的计数中去掉，并创建一个新的worker补上容量
+func (p *Pool) abandonWorker(d time.Duration) {
+	atomic.AddUint64(&p.abandonedWorkers, 1)
+	p.taskFinished()
+	p.decRunning()
+	atomic.AddInt32(&p.alive, -1)
+	p.markNotFull()
+	p.options.Logger.Printf("ants: worker abandoned, task has been running for more than %v\n", d)
+
+	if p.IsClosed() {
+		p.cond.Signal()
+		return
+	}
+	w := p.workerCache.Get().(*goWorker)
+	w.run()
+	if !p.revertWorker(w) {
+		w.task <- nil
+		p.cond.Signal()
+	}
+}
@@ -0,0 +1,37 @@
+package ants
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxTaskDuration(t *testing.T) {
+	p, err := NewPool(1, WithMaxTaskDuration(50*time.Millisecond), WithNonblocking(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	stuck := make(chan struct{})
+	assert.NoError(t, p.Submit(func() { <-stuck }))
+	assert.Equal(t, ErrPoolOverload, p.Submit(demoFunc), "pool should be full while the task is running")
+
+	// 超时之后卡住的worker被放弃，新的worker补上容量
+	time.Sleep(100 * time.Millisecond)
+	stats := p.Stats()
+	assert.EqualValues(t, 1, stats.AbandonedWorkers)
+	assert.EqualValues(t, 1, stats.Running, "only the replacement worker should be counted")
+	assert.EqualValues(t, 1, stats.Idle)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, p.Submit(wg.Done), "capacity should be restored by the replacement worker")
+	wg.Wait()
+
+	// 卡住的任务结束之后goroutine直接退出，不会再影响计数
+	close(stuck)
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 1, p.Running())
+	assert.EqualValues(t, 1, p.Stats().AbandonedWorkers)
+}
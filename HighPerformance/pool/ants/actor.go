@@ -0,0 +1,119 @@
+package ants
+
+import (
+	"sync"
+	"time"
+)
+
+// Actor 把发送给它的消息按顺序交给同一个worker一个接一个地执行，消息的处理函数之间不会并发，不需要加锁。
+// 多个Actor共享pool的worker，有消息的时候才占用一个worker，消息处理完之后再等待linger，还没有新消息的话就把worker还给pool
+type Actor struct {
+	pool *Pool
+
+	// lock 保护linger、queue和running
+	lock   sync.Mutex
+	linger time.Duration
+	queue  []func()
+	// running 是否有一个worker正在处理这个Actor的消息
+	running bool
+
+	// wake 通知正在等待新消息的worker
+	wake chan struct{}
+}
+
+// NewActor 创建一个使用p的worker处理消息的Actor，默认处理完消息之后马上归还worker，见SetLinger
+func NewActor(p *Pool) *Actor {
+	return &Actor{
+		pool: p,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// SetLinger 设置消息处理完之后继续占用worker等待新消息的时间，连续发送消息的时候可以避免反复提交任务的开销
+func (a *Actor) SetLinger(linger time.Duration) {
+	a.lock.Lock()
+	a.linger = linger
+	a.lock.Unlock()
+}
+
+// Send 发送一条消息，它会在之前发送的消息都处理完之后执行。没有worker在处理这个Actor的消息的时候，
+// 会向pool提交一个任务，pool已满的时候和Submit一样阻塞或者返回错误，提交失败的时候已经排队的消息会被丢弃
+func (a *Actor) Send(msg func()) error {
+	if a.pool.IsClosed() {
+		return ErrPoolClosed
+	}
+	a.lock.Lock()
+	a.queue = append(a.queue, msg)
+	if a.running {
+		a.lock.Unlock()
+		select {
+		case a.wake <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	a.running = true
+	a.lock.Unlock()
+	return a.dispatch()
+}
+
+// dispatch 提交一个处理消息的任务到pool中
+func (a *Actor) dispatch() error {
+	err := a.pool.Submit(a.run)
+	if err != nil {
+		a.lock.Lock()
+		a.queue = nil
+		a.running = false
+		a.lock.Unlock()
+	}
+	return err
+}
+
+// run 依次处理消息，直到没有新的消息
+func (a *Actor) run() {
+	var msg func()
+	defer func() {
+		// msg不为nil说明消息的处理发生了panic，剩下的消息换一个worker继续处理，panic仍然交给pool处理
+		if msg != nil {
+			go func() { _ = a.dispatch() }()
+		}
+	}()
+	for msg = a.next(); msg != nil; msg = a.next() {
+		msg()
+	}
+}
+
+// next 取出下一条消息，没有消息的时候最多等待linger，还是没有的话返回nil，之后的Send会重新提交任务
+func (a *Actor) next() func() {
+	a.lock.Lock()
+	for len(a.queue) == 0 {
+		linger := a.linger
+		if linger <= 0 {
+			a.running = false
+			a.lock.Unlock()
+			return nil
+		}
+		a.lock.Unlock()
+		timer := time.NewTimer(linger)
+		var expired bool
+		select {
+		case <-a.wake:
+		case <-timer.C:
+			expired = true
+		case <-a.pool.ShutdownContext().Done():
+			expired = true
+		}
+		timer.Stop()
+		a.lock.Lock()
+		if expired && len(a.queue) == 0 {
+			a.running = false
+			a.lock.Unlock()
+			return nil
+		}
+	}
+	msg := a.queue[0]
+	a.queue[0] = nil
+	a.queue = a.queue[1:]
+	a.lock.Unlock()
+	return msg
+}
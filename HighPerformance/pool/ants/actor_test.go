@@ -0,0 +1,103 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActor(t *testing.T) {
+	// worker执行完任务之后不会马上回到pool，容量要足够大
+	p, err := NewPool(200, WithPanicHandler(func(interface{}) {}))
+	assert.NoError(t, err)
+	defer p.Release()
+
+	a := NewActor(p)
+	var (
+		got     []int
+		active  int32
+		overlap int32
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 100; i++ {
+		i := i
+		wg.Add(1)
+		assert.NoError(t, a.Send(func() {
+			defer wg.Done()
+			if atomic.AddInt32(&active, 1) > 1 {
+				atomic.StoreInt32(&overlap, 1)
+			}
+			got = append(got, i)
+			atomic.AddInt32(&active, -1)
+		}))
+	}
+	wg.Wait()
+	assert.EqualValues(t, 0, overlap, "messages of one actor should never run concurrently")
+	for i, v := range got {
+		assert.Equal(t, i, v, "messages should run in order")
+	}
+
+	// 处理消息的时候发生panic，之后的消息仍然按顺序处理
+	got = nil
+	wg.Add(2)
+	assert.NoError(t, a.Send(func() {
+		defer wg.Done()
+		panic("boom")
+	}))
+	assert.NoError(t, a.Send(func() {
+		defer wg.Done()
+		got = append(got, 1)
+	}))
+	wg.Wait()
+	assert.Equal(t, []int{1}, got)
+}
+
+func TestActorsRunInParallel(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	// 两个Actor的消息互相等待，只有并行执行才能都完成
+	var started sync.WaitGroup
+	started.Add(2)
+	done := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, NewActor(p).Send(func() {
+			started.Done()
+			started.Wait()
+			done <- struct{}{}
+		}))
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("actors should run in parallel")
+		}
+	}
+}
+
+func TestActorLinger(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	a := NewActor(p)
+	a.SetLinger(50 * time.Millisecond)
+	done := make(chan struct{})
+	assert.NoError(t, a.Send(func() { done <- struct{}{} }))
+	<-done
+	// 等待新消息的时候还占用着worker，新消息由同一个任务处理
+	assert.Equal(t, 1, p.InFlight())
+	assert.NoError(t, a.Send(func() { done <- struct{}{} }))
+	<-done
+	assert.Equal(t, 1, p.Running())
+	eventually(t, func() bool { return p.InFlight() == 0 }, time.Second, time.Millisecond,
+		"worker should be released after lingering")
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, a.Send(func() {}))
+}
@@ -0,0 +1,16 @@
+package ants
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// pinWorker 把当前worker的goroutine锁定到一个OS线程上，再把这个线程绑定到cpus中轮流选出的一个CPU上，
+// 不调用UnlockOSThread，worker退出的时候这个被修改过亲和性的线程也会随之退出，不会被其它goroutine复用
+func pinWorker(cpus []int, cursor *uint32, logger Logger) {
+	runtime.LockOSThread()
+	cpu := cpus[(atomic.AddUint32(cursor, 1)-1)%uint32(len(cpus))]
+	if err := setAffinity(cpu); err != nil {
+		logger.Printf("worker failed to set cpu affinity to %d: %v\n", cpu, err)
+	}
+}
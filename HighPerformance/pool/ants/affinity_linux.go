@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package ants
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// cpuSetSize 和glibc中cpu_set_t的大小一致，最多支持1024个CPU
+const cpuSetSize = 1024
+
+// setAffinity 把当前线程绑定到指定的CPU上，测试的时候可以替换掉
+var setAffinity = func(cpu int) error {
+	if cpu < 0 || cpu >= cpuSetSize {
+		return syscall.EINVAL
+	}
+	var mask [cpuSetSize / 64]uint64
+	mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	// pid为0代表当前线程
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
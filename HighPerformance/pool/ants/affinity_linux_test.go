@@ -0,0 +1,94 @@
+//go:build linux
+// +build linux
+
+package ants
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPUAffinity(t *testing.T) {
+	var (
+		lock sync.Mutex
+		cpus []int
+	)
+	setAffinityOrigin := setAffinity
+	defer func() { setAffinity = setAffinityOrigin }()
+	setAffinity = func(cpu int) error {
+		lock.Lock()
+		cpus = append(cpus, cpu)
+		lock.Unlock()
+		return nil
+	}
+
+	p, err := NewPool(10, WithCPUAffinity([]int{2, 3}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 三个任务同时运行，需要三个worker
+	var wg sync.WaitGroup
+	ch := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		_ = p.Submit(func() {
+			wg.Done()
+			<-ch
+		})
+	}
+	wg.Wait()
+	close(ch)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Len(t, cpus, 3, "affinity should be set once per worker")
+	assert.ElementsMatch(t, []int{2, 3, 2}, cpus, "cpus should be assigned to workers in rotation")
+
+	assert.Error(t, setAffinityOrigin(cpuSetSize), "cpu out of range should be rejected")
+}
+
+// threadLogger 记录日志的内容
+type threadLogger struct {
+	lock sync.Mutex
+	logs []string
+}
+
+func (l *threadLogger) Printf(format string, args ...interface{}) {
+	l.lock.Lock()
+	l.logs = append(l.logs, fmt.Sprintf(format, args...))
+	l.lock.Unlock()
+}
+
+func TestOSThreads(t *testing.T) {
+	p, err := NewPool(10, WithOSThreads())
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 锁定了线程的worker不管让出多少次执行权，都在同一个线程上运行
+	tids := make(chan int, 100)
+	done := make(chan struct{})
+	_ = p.Submit(func() {
+		defer close(done)
+		for i := 0; i < cap(tids); i++ {
+			tids <- syscall.Gettid()
+			runtime.Gosched()
+		}
+	})
+	<-done
+	close(tids)
+	first := <-tids
+	for tid := range tids {
+		assert.Equal(t, first, tid, "worker should stay on the same OS thread")
+	}
+
+	logger := &threadLogger{}
+	p1, err := NewPool(-1, WithOSThreads(), WithLogger(logger))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	assert.Len(t, logger.logs, 1, "unlimited pool with OS threads should be warned")
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package ants
+
+// setAffinity 在非Linux平台上什么也不做
+var setAffinity = func(cpu int) error {
+	return nil
+}
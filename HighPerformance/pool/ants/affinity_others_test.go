@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package ants
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPUAffinity(t *testing.T) {
+	assert.NoError(t, setAffinity(0), "setting affinity should be a no-op")
+
+	p, err := NewPool(10, WithCPUAffinity([]int{0}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	done := make(chan struct{})
+	assert.NoError(t, p.Submit(func() { close(done) }))
+	<-done
+}
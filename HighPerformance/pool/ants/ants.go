@@ -23,7 +23,7 @@
 package ants
 
 import (
-	"errors"
+	"fmt"
 	"log"
 	"math"
 	"os"
@@ -37,6 +37,12 @@ const (
 
 	// 默认清理goroutine的时间间隔
 	DefaultCleanIntervalTime = time.Second
+
+	// pool变满之后要保持这么久才会调用OnFull
+	onFullDebounce = 10 * time.Millisecond
+
+	// maxInt 是int的最大值，不限制容量的pool的Free()返回它
+	maxInt = int(^uint(0) >> 1)
 )
 
 const (
@@ -51,24 +57,88 @@ var (
 	// 定义了错误的类型.
 	//---------------------------------------------------------------------------
 
+	// ErrBusy is the category of errors returned when the pool has no worker available for a task,
+	// use errors.Is(err, ErrBusy) to match all of them.
+	ErrBusy = newPoolError("pool is busy", nil)
+
+	// ErrInvalidArgument is the category of errors returned when an argument or option passed to the pool is invalid,
+	// use errors.Is(err, ErrInvalidArgument) to match all of them.
+	ErrInvalidArgument = newPoolError("invalid argument", nil)
+
 	// ErrInvalidPoolSize will be returned when setting a negative number as pool capacity, this error will be only used
 	// by pool with func because pool without func can be infinite by setting up a negative capacity.
-	ErrInvalidPoolSize = errors.New("invalid size for pool")
+	ErrInvalidPoolSize = newPoolError("invalid size for pool", ErrInvalidArgument)
 
 	// ErrLackPoolFunc will be returned when invokers don't provide function for pool.
-	ErrLackPoolFunc = errors.New("must provide function for pool")
+	ErrLackPoolFunc = newPoolError("must provide function for pool", ErrInvalidArgument)
 
 	// ErrInvalidPoolExpiry will be returned when setting a negative number as the periodic duration to purge goroutines.
-	ErrInvalidPoolExpiry = errors.New("invalid expiry for pool")
+	ErrInvalidPoolExpiry = newPoolError("invalid expiry for pool", ErrInvalidArgument)
 
 	// ErrPoolClosed will be returned when submitting task to a closed pool.
-	ErrPoolClosed = errors.New("this pool has been closed")
+	ErrPoolClosed = newPoolError("this pool has been closed", nil)
 
 	// ErrPoolOverload will be returned when the pool is full and no workers available.
-	ErrPoolOverload = errors.New("too many goroutines blocked on submit or Nonblocking is set")
+	ErrPoolOverload = newPoolError("too many goroutines blocked on submit or Nonblocking is set", ErrBusy)
 
 	// ErrInvalidPreAllocSize will be returned when trying to set up a negative capacity under PreAlloc mode.
-	ErrInvalidPreAllocSize = errors.New("can not set up a negative capacity under PreAlloc mode")
+	ErrInvalidPreAllocSize = newPoolError("can not set up a negative capacity under PreAlloc mode", ErrInvalidArgument)
+
+	// ErrInvalidPoolConfig will be returned when a PoolConfig contains values that can not be translated into options.
+	ErrInvalidPoolConfig = newPoolError("invalid config for pool", ErrInvalidArgument)
+
+	// ErrContextCancelled will be returned when the context is done before a task has been submitted.
+	ErrContextCancelled = newPoolError("context has been cancelled before the task was submitted", nil)
+
+	// ErrUnhealthy will be returned by HealthCheck when the probe task does not complete within the probe timeout.
+	ErrUnhealthy = newPoolError("pool is unhealthy: probe task did not complete in time", nil)
+
+	// ErrSpawnExceedsCap will be returned when trying to spawn more workers than the capacity of pool.
+	ErrSpawnExceedsCap = newPoolError("can not spawn more workers than the capacity of pool", ErrInvalidArgument)
+
+	// ErrPanicStorm will be returned when too many tasks panicked recently and the pool is rejecting new tasks for a while.
+	ErrPanicStorm = newPoolError("too many tasks panicked recently, pool is rejecting new tasks", ErrBusy)
+
+	// ErrPreempted will be returned when a waiting submission is displaced by a higher-priority one under PreemptiveQueue.
+	ErrPreempted = newPoolError("task has been preempted by a higher-priority task", ErrBusy)
+
+	// ErrIncompatibleOptions will be returned by RebootWith when the new options change settings that can not be changed
+	// after the pool has been created, such as PreAlloc, Synchronous and LockProfiling.
+	ErrIncompatibleOptions = newPoolError("options can not be changed on reboot", ErrInvalidArgument)
+
+	// ErrRebootNotReady will be returned by RebootWith when the pool is not closed or still has running workers.
+	ErrRebootNotReady = newPoolError("pool can not be rebooted until it is closed and drained", ErrBusy)
+
+	// ErrCapacityFixed will be returned by AdjustCapacity when the pool is unlimited, PreAlloc or Synchronous.
+	ErrCapacityFixed = newPoolError("capacity of this pool can not be adjusted", ErrInvalidArgument)
+
+	// ErrCapacityBelowRunning will be returned by AdjustCapacity when the new capacity would be less than Running().
+	ErrCapacityBelowRunning = newPoolError("can not adjust capacity below the number of running workers", ErrInvalidArgument)
+
+	// ErrTaskCancelled will be returned by Task.Await when the task has been cancelled before it started,
+	// and by Submit when the submitter was blocked waiting for a worker and CancelPending was called.
+	ErrTaskCancelled = newPoolError("task has been cancelled before it started", nil)
+
+	// ErrInvalidMemEstimate will be returned by SubmitWithMemEstimate when the estimate is negative or exceeds the whole MemoryBudget.
+	ErrInvalidMemEstimate = newPoolError("memory estimate of task is negative or exceeds the memory budget", ErrInvalidArgument)
+
+	// ErrInsufficientCapacity will be returned by SubmitNAtomic when the pool can not take all the tasks at once.
+	ErrInsufficientCapacity = newPoolError("not enough capacity to submit all the tasks at once", ErrBusy)
+
+	// ErrUnknownClass will be returned by Router.Submit when the class of a task has no pool and there is no default pool.
+	ErrUnknownClass = newPoolError("no pool for the class of task", ErrInvalidArgument)
+
+	// ErrSubmitTimeout will be returned by SubmitTimeout when no worker becomes available within the timeout.
+	ErrSubmitTimeout = newPoolError("timed out waiting for an available worker", ErrBusy)
+
+	// ErrNilWorkerArray will be returned by SwapWorkerArray when the new WorkerArray is nil.
+	ErrNilWorkerArray = newPoolError("worker array must not be nil", ErrInvalidArgument)
+
+	// ErrDelayQueueFull will be returned by SubmitAfter and SubmitAfterMany when the tasks would exceed MaxDelayedTasks.
+	ErrDelayQueueFull = newPoolError("too many delayed tasks waiting", ErrBusy)
+
+	// ErrNotSlice will be returned by ForEach when items is not a slice.
+	ErrNotSlice = newPoolError("items must be a slice", ErrInvalidArgument)
 
 	//---------------------------------------------------------------------------
 
@@ -93,6 +163,56 @@ var (
 	defaultAntsPool, _ = NewPool(DefaultAntsPoolSize)
 )
 
+// PanicError 包装了任务中发生的panic，用于需要把任务的结果以error的形式返回给调用者的场景
+type PanicError struct {
+	// Value 是recover得到的值
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// monotonicBase 是加载包时记录的时间，它带有单调时钟读数
+var monotonicBase = time.Now()
+
+// nanotime 返回从monotonicBase开始经过的纳秒数，只依赖单调时钟，不受系统墙上时间调整的影响
+func nanotime() int64 {
+	return int64(time.Since(monotonicBase))
+}
+
+// taskChanCap 根据pool的容量和缓冲的总字节数上限计算每个worker的任务channel的缓冲大小，
+// elemSize是channel中一个元素的大小，结果不会超过workerChanCap
+func taskChanCap(capacity, maxBytes int, elemSize uintptr) int {
+	if maxBytes <= 0 || capacity <= 0 {
+		return workerChanCap
+	}
+	if c := maxBytes / (capacity * int(elemSize)); c < workerChanCap {
+		return c
+	}
+	return workerChanCap
+}
+
+// PoolError 是pool返回的错误，Unwrap返回它所属的类别，可以用errors.Is(err, ErrBusy)这样的方式匹配一类错误，
+// 也可以用errors.As取出*PoolError来区分pool的错误和任务自己的错误
+type PoolError struct {
+	msg      string
+	category error
+}
+
+func newPoolError(msg string, category error) error {
+	return &PoolError{msg: msg, category: category}
+}
+
+func (e *PoolError) Error() string {
+	return e.msg
+}
+
+// Unwrap 返回错误所属的类别，类别本身的Unwrap返回nil
+func (e *PoolError) Unwrap() error {
+	return e.category
+}
+
 // Logger is used for logging formatted messages.
 type Logger interface {
 	// Printf must have the same semantics as log.Printf.
@@ -124,7 +244,7 @@ func Release() {
 	defaultAntsPool.Release()
 }
 
-// Reboot 重启默认的pool
-func Reboot() {
-	defaultAntsPool.Reboot()
+// Reboot 重启默认的pool，返回是否真的重启了
+func Reboot() bool {
+	return defaultAntsPool.Reboot()
 }
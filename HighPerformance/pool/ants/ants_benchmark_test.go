@@ -23,10 +23,14 @@
 package ants
 
 import (
+	"context"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -47,6 +51,22 @@ func demoPoolFunc(args interface{}) {
 	time.Sleep(time.Duration(n) * time.Millisecond)
 }
 
+// eventually 在waitFor时间内每隔tick在当前goroutine中检查一次condition，超时之后报告失败。
+// 代替testify v1.4.0的assert.Eventually：它每次检查都启动一个goroutine，条件满足之后还在运行的检查会向已经关闭的channel发送而panic
+func eventually(t *testing.T, condition func() bool, waitFor, tick time.Duration, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	deadline := time.Now().Add(waitFor)
+	for {
+		if condition() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return assert.Fail(t, "Condition never satisfied", msgAndArgs...)
+		}
+		time.Sleep(tick)
+	}
+}
+
 func longRunningFunc() {
 	for {
 		runtime.Gosched()
@@ -146,3 +166,171 @@ func BenchmarkAntsPoolThroughput(b *testing.B) {
 	}
 	b.StopTimer()
 }
+
+// countingLocker 统计加锁的次数
+type countingLocker struct {
+	sync.Locker
+	n int64
+}
+
+func (l *countingLocker) Lock() {
+	atomic.AddInt64(&l.n, 1)
+	l.Locker.Lock()
+}
+
+// benchmarkPingPong 模拟提交→完成→提交的负载，反复取出和归还同一个worker
+func benchmarkPingPong(b *testing.B, options ...Option) {
+	p, _ := NewPool(1, append(options, WithDisablePurge(true))...)
+	defer p.Release()
+	locker := &countingLocker{Locker: p.lock}
+	p.lock = locker
+	p.cond = sync.NewCond(locker)
+	p.revertWorker(&goWorker{pool: p, task: make(chan func(), 1)})
+	atomic.StoreInt64(&locker.n, 0)
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.revertWorker(p.retrieveWorker(ctx))
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(atomic.LoadInt64(&locker.n))/float64(b.N), "locks/op")
+}
+
+func BenchmarkPingPong(b *testing.B) {
+	benchmarkPingPong(b)
+}
+
+func BenchmarkPingPongHotWorker(b *testing.B) {
+	benchmarkPingPong(b, WithHotWorker(true))
+}
+
+// benchmarkSpinWait 多个提交者争抢一个worker，任务很快就能执行完，worker执行完之后马上归还
+func benchmarkSpinWait(b *testing.B, options ...Option) {
+	p, _ := NewPool(1, append(options, WithDisablePurge(true))...)
+	defer p.Release()
+	p.incRunning()
+	defer p.decRunning()
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	go func() {
+		for f := range w.task {
+			if f == nil {
+				return
+			}
+			f()
+			p.revertWorker(w)
+		}
+	}()
+	p.revertWorker(w)
+
+	ctx := context.Background()
+	b.SetParallelism(2)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p.retrieveWorker(ctx).task <- func() {}
+		}
+	})
+}
+
+func BenchmarkSubmitOnFull(b *testing.B) {
+	benchmarkSpinWait(b)
+}
+
+func BenchmarkSubmitOnFullSpinWait(b *testing.B) {
+	benchmarkSpinWait(b, WithSpinWaitOnFull(100))
+}
+
+// BenchmarkWakeBlockedSubmitters 几百个调用者阻塞在已满的pool上，扩容之后只有少数能拿到worker，
+// 只唤醒需要的数量的时候其余的等待者不会醒来争抢pool.lock
+func BenchmarkWakeBlockedSubmitters(b *testing.B) {
+	const waiters, grow = 500, 10
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p, _ := NewPool(1, WithDisablePurge(true))
+		p.incRunning()
+		workers := make(chan *goWorker, waiters)
+		for j := 0; j < waiters; j++ {
+			go func() {
+				workers <- p.retrieveWorker(ctx)
+			}()
+		}
+		for atomic.LoadInt32(&p.blockingNum) < waiters {
+			runtime.Gosched()
+		}
+		b.StartTimer()
+
+		p.Tune(1 + grow)
+		for j := 0; j < grow; j++ {
+			(<-workers).task <- nil
+		}
+
+		b.StopTimer()
+		p.Release()
+		for j := grow; j < waiters; j++ {
+			if w := <-workers; w != nil {
+				w.task <- nil
+			}
+		}
+		p.decRunning()
+		b.StartTimer()
+	}
+}
+
+// benchmarkReject 在已满的pool上提交任务，测试拒绝路径的开销
+func benchmarkReject(b *testing.B, submit func(p *Pool) bool) {
+	p, _ := NewPool(1, WithNonblocking(true), WithDisablePurge(true))
+	defer p.Release()
+	p.incRunning()
+	defer p.decRunning()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if submit(p) {
+			b.Fatal("submit to a full pool should be rejected")
+		}
+	}
+}
+
+func BenchmarkSubmitReject(b *testing.B) {
+	benchmarkReject(b, func(p *Pool) bool {
+		return p.Submit(demoFunc) == nil
+	})
+}
+
+func BenchmarkTrySubmitFastReject(b *testing.B) {
+	benchmarkReject(b, func(p *Pool) bool {
+		return p.TrySubmitFast(demoFunc)
+	})
+}
+
+// benchmarkFirstBurst 新创建的pool收到第一波突发的任务，需要创建burst个worker，只统计创建worker的分配
+func benchmarkFirstBurst(b *testing.B, options ...Option) {
+	const burst = 1000
+	ctx := context.Background()
+	workers := make([]*goWorker, burst)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p, _ := NewPool(burst, append(options, WithPreAlloc(true), WithDisablePurge(true))...)
+		b.StartTimer()
+		for j := range workers {
+			workers[j] = p.retrieveWorker(ctx)
+		}
+		b.StopTimer()
+		for _, w := range workers {
+			w.task <- nil
+		}
+		p.Release()
+	}
+}
+
+func BenchmarkFirstBurst(b *testing.B) {
+	benchmarkFirstBurst(b)
+}
+
+func BenchmarkFirstBurstWarmCache(b *testing.B) {
+	benchmarkFirstBurst(b, WithWarmWorkerCache(1000))
+}
@@ -23,6 +23,9 @@
 package ants
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -32,6 +35,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/panjf2000/ants/v2/internal"
 	"github.com/stretchr/testify/assert"
 	_ "net/http/pprof"
 )
@@ -499,6 +503,37 @@ func TestMaxBlockingSubmit(t *testing.T) {
 	}
 }
 
+func TestSubmitOrBlock(t *testing.T) {
+	p, err := NewPool(1, WithMaxBlockingTasks(1))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 占满pool，再让一个普通的Submit阻塞住
+	p.incRunning()
+	defer p.decRunning()
+	errCh := make(chan error, 2)
+	go func() { errCh <- p.Submit(demoFunc) }()
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 1 }, time.Second, time.Millisecond)
+	assert.EqualError(t, p.Submit(demoFunc), ErrPoolOverload.Error(),
+		"blocking submit when pool reach max blocking submit should return ErrPoolOverload")
+
+	// SubmitOrBlock不受MaxBlockingTasks的限制，继续阻塞等待
+	go func() { errCh <- p.SubmitOrBlock(demoFunc) }()
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 2 }, time.Second, time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		w := &goWorker{pool: p, task: make(chan func(), 1)}
+		assert.True(t, p.revertWorker(w))
+		(<-w.task)()
+	}
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, <-errCh, "blocked submit should succeed once a worker is available")
+	}
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.SubmitOrBlock(demoFunc))
+}
+
 func TestNonblockingSubmitWithFunc(t *testing.T) {
 	poolSize := 10
 	ch1 := make(chan struct{})
@@ -715,3 +750,1664 @@ func TestRestCodeCoverage(t *testing.T) {
 	t.Logf("pre-malloc pool with func, after tuning capacity, capacity:%d, running:%d", ppremWithFunc.Cap(),
 		ppremWithFunc.Running())
 }
+
+func TestSubmitBatch(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var wg sync.WaitGroup
+	var counter int32
+	tasks := make([]func(), 5)
+	for i := range tasks {
+		tasks[i] = func() {
+			atomic.AddInt32(&counter, 1)
+			wg.Done()
+		}
+	}
+	wg.Add(len(tasks))
+	for i, err := range p.SubmitBatch(tasks) {
+		assert.NoErrorf(t, err, "task %d should be submitted", i)
+	}
+	wg.Wait()
+	assert.EqualValues(t, len(tasks), atomic.LoadInt32(&counter))
+
+	p.Release()
+	for _, err := range p.SubmitBatch(tasks) {
+		assert.Equal(t, ErrPoolClosed, err)
+	}
+}
+
+func TestSubmitBatchCtx(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	ch := make(chan struct{})
+	defer close(ch)
+	tasks := []func(){
+		func() { <-ch },
+		demoFunc,
+		demoFunc,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// 第二个任务会阻塞在等待worker上
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	errs := p.SubmitBatchCtx(ctx, tasks)
+	assert.NoError(t, errs[0], "first task should be submitted")
+	assert.Equal(t, ErrContextCancelled, errs[1])
+	assert.Equal(t, ErrContextCancelled, errs[2])
+
+	// 已经取消的ctx不会提交任何任务
+	for _, err := range p.SubmitBatchCtx(ctx, tasks) {
+		assert.Equal(t, ErrContextCancelled, err)
+	}
+}
+
+func TestPurgeRestartAfterPanic(t *testing.T) {
+	expiry := 50 * time.Millisecond
+	var (
+		panicked int32
+		target   atomic.Value
+	)
+	// 只让这个测试的pool发生panic
+	purgeHook.Store(func(pool *Pool) {
+		if target.Load() == pool && atomic.CompareAndSwapInt32(&panicked, 0, 1) {
+			panic("purge Oops!")
+		}
+	})
+	p, err := NewPool(10, WithExpiryDuration(expiry))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer func() {
+		p.Release()
+		purgeHook.Store(func(*Pool) {})
+	}()
+	// 一个会过期的空闲worker，清理goroutine发生panic之后也要能通知到它退出
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.incRunning()
+	defer p.decRunning()
+	assert.True(t, p.revertWorker(w))
+	target.Store(p)
+	assert.False(t, p.PurgeStale(10*expiry), "purge goroutine should be alive")
+
+	time.Sleep(3 * expiry)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&panicked), "purge hook should have panicked")
+	eventually(t, func() bool { return len(w.task) == 1 }, time.Second, 10*time.Millisecond,
+		"expired worker should still be told to stop after the panic")
+	assert.Nil(t, <-w.task)
+	last := atomic.LoadInt64(&p.lastPurgeTime)
+	time.Sleep(3 * expiry)
+	assert.Greater(t, atomic.LoadInt64(&p.lastPurgeTime), last, "lastPurgeTime should keep advancing after restart")
+	assert.False(t, p.PurgeStale(10*expiry), "purge goroutine should be restarted")
+
+	p1, err := NewPool(10, WithDisablePurge(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	assert.False(t, p1.PurgeStale(0), "pool without purge goroutine should never be stale")
+}
+
+func TestPurgeDoesNotStarveBlockedSubmit(t *testing.T) {
+	p, err := NewPool(2, WithExpiryDuration(time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 两个执行完任务马上归还的worker，被清理的时候重新归还，保持pool一直是满的
+	for i := 0; i < 2; i++ {
+		w := &goWorker{pool: p, task: make(chan func(), 1)}
+		p.incRunning()
+		defer p.decRunning()
+		go func() {
+			for f := range w.task {
+				if f != nil {
+					f()
+				}
+				p.revertWorker(w)
+			}
+		}()
+		p.revertWorker(w)
+	}
+
+	const submitters, tasks = 50, 50
+	var (
+		wg   sync.WaitGroup
+		done int32
+	)
+	last := atomic.LoadInt64(&p.lastPurgeTime)
+	for i := 0; i < submitters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < tasks; j++ {
+				w := p.retrieveWorker(context.Background())
+				if w == nil {
+					return
+				}
+				w.task <- func() { atomic.AddInt32(&done, 1) }
+			}
+		}()
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("blocked submitters starved: %d/%d tasks submitted", atomic.LoadInt32(&done), submitters*tasks)
+	}
+	assert.Greater(t, atomic.LoadInt64(&p.lastPurgeTime), last, "purge should keep running")
+	eventually(t, func() bool { return atomic.LoadInt32(&done) == submitters*tasks },
+		time.Second, 10*time.Millisecond)
+}
+
+func TestSwapWorkerArray(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	idle := make([]*goWorker, 3)
+	p.lock.Lock()
+	for i := range idle {
+		idle[i] = &goWorker{pool: p, task: make(chan func(), 1), recycleTime: nanotime()}
+		_ = p.workers.Insert(idle[i])
+	}
+	p.lock.Unlock()
+
+	_, err = p.SwapWorkerArray(nil)
+	assert.EqualError(t, err, ErrNilWorkerArray.Error())
+
+	old, err := p.SwapWorkerArray(NewWorkerArray(LoopQueueType, 2))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, old.Len(), "old array should be drained")
+	p.lock.Lock()
+	assert.EqualValues(t, 2, p.workers.Len(), "new array should hold the idle workers")
+	p.lock.Unlock()
+
+	// 栈是后进先出的，最先插入的worker放不下，会被通知退出
+	select {
+	case task := <-idle[0].task:
+		assert.Nil(t, task, "overflowed worker should be stopped")
+	default:
+		t.Fatalf("overflowed worker should be notified to stop")
+	}
+	assert.EqualValues(t, 0, len(idle[1].task), "migrated worker shouldn't be stopped")
+	assert.EqualValues(t, 0, len(idle[2].task), "migrated worker shouldn't be stopped")
+}
+
+func TestSubmitWithValues(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var wg sync.WaitGroup
+	var requestID, missing interface{}
+	wg.Add(1)
+	err = p.SubmitWithValues(map[string]interface{}{"requestID": "req-1"}, func() {
+		defer wg.Done()
+		requestID = FromTask("requestID")
+		missing = FromTask("missing")
+	})
+	assert.NoError(t, err, "submit with values shouldn't return error")
+	wg.Wait()
+	assert.Equal(t, "req-1", requestID)
+	assert.Nil(t, missing)
+
+	// 普通的任务中读取不到值
+	wg.Add(1)
+	_ = p.Submit(func() {
+		defer wg.Done()
+		requestID = FromTask("requestID")
+	})
+	wg.Wait()
+	assert.Nil(t, requestID, "values shouldn't leak into other tasks")
+	assert.Nil(t, FromTask("requestID"), "values shouldn't be visible outside tasks")
+}
+
+func TestHotWorkerNoTaskLoss(t *testing.T) {
+	const workers = 4
+	p, err := NewPool(workers, WithHotWorker(true), WithDisablePurge(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 用假的worker模拟正在运行的goroutine，检查并发地取出和归还时每个worker同一时刻只会被一个调用者拿到
+	inUse := make(map[*goWorker]*int32, workers)
+	for i := 0; i < workers; i++ {
+		w := &goWorker{pool: p, task: make(chan func(), 1)}
+		inUse[w] = new(int32)
+		p.incRunning()
+		assert.True(t, p.revertWorker(w), "revert worker failed")
+	}
+
+	var wg sync.WaitGroup
+	var duplicated int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				w := p.retrieveWorker(context.Background())
+				if !atomic.CompareAndSwapInt32(inUse[w], 0, 1) {
+					atomic.AddInt32(&duplicated, 1)
+				}
+				atomic.StoreInt32(inUse[w], 0)
+				p.revertWorker(w)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 0, duplicated, "a worker shouldn't be handed out twice")
+
+	p.lock.Lock()
+	idle := p.workers.Len()
+	p.lock.Unlock()
+	if atomic.LoadPointer(&p.hotWorker) != nil {
+		idle++
+	}
+	assert.EqualValues(t, workers, idle, "no worker should be lost")
+
+	// 真正地提交任务
+	p1, err := NewPool(-1, WithHotWorker(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	var counter int32
+	wg.Add(AntsSize)
+	for i := 0; i < AntsSize; i++ {
+		_ = p1.Submit(func() {
+			atomic.AddInt32(&counter, 1)
+			wg.Done()
+		})
+	}
+	wg.Wait()
+	assert.EqualValues(t, AntsSize, counter, "no task should be lost")
+}
+
+func TestMigrateTo(t *testing.T) {
+	p, err := NewPool(4, WithPreAlloc(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var (
+		wg      sync.WaitGroup
+		started int32
+		counter int32
+	)
+	ch := make(chan struct{})
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			_ = p.Submit(func() {
+				atomic.AddInt32(&started, 1)
+				<-ch
+				atomic.AddInt32(&counter, 1)
+				wg.Done()
+			})
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, 4, atomic.LoadInt32(&started), "only 4 tasks should run before migration")
+
+	assert.NoError(t, p.MigrateTo(16), "migrate pool failed")
+	assert.EqualValues(t, 16, p.Cap())
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, 16, atomic.LoadInt32(&started), "all tasks should run after migration")
+	close(ch)
+	wg.Wait()
+	assert.EqualValues(t, 16, atomic.LoadInt32(&counter), "no task should be lost")
+
+	assert.Equal(t, ErrInvalidPoolSize, p.MigrateTo(0))
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.MigrateTo(8))
+}
+
+func TestOnTaskDone(t *testing.T) {
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		durations []time.Duration
+		panics    []interface{}
+	)
+	onTaskDone := func(dur time.Duration, recovered interface{}) {
+		defer wg.Done()
+		lock.Lock()
+		durations = append(durations, dur)
+		panics = append(panics, recovered)
+		lock.Unlock()
+	}
+	p, err := NewPool(10, WithOnTaskDone(onTaskDone), WithPanicHandler(func(interface{}) {}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	wg.Add(1)
+	_ = p.Submit(func() { time.Sleep(20 * time.Millisecond) })
+	wg.Wait()
+	wg.Add(1)
+	_ = p.Submit(func() { panic("Oops!") })
+	wg.Wait()
+
+	assert.Len(t, durations, 2, "OnTaskDone should be invoked for every task")
+	assert.True(t, durations[0] >= 20*time.Millisecond && durations[0] < time.Second,
+		"duration should be plausible: %v", durations[0])
+	assert.Nil(t, panics[0], "recovered should be nil on success")
+	assert.Equal(t, "Oops!", panics[1], "recovered should be the panic value")
+
+	p1, err := NewPoolWithFunc(10, func(i interface{}) { panic(i) },
+		WithOnTaskDone(onTaskDone), WithPanicHandler(func(interface{}) {}))
+	assert.NoErrorf(t, err, "create PoolWithFunc failed: %v", err)
+	defer p1.Release()
+	wg.Add(1)
+	_ = p1.Invoke("Oops!")
+	wg.Wait()
+	assert.Len(t, panics, 3, "OnTaskDone should be invoked for PoolWithFunc")
+	assert.Equal(t, "Oops!", panics[2])
+}
+
+func TestPurgeSkippedWhileBlocking(t *testing.T) {
+	expiry := 50 * time.Millisecond
+	p, err := NewPool(10, WithExpiryDuration(expiry))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	w := &goWorker{pool: p, task: make(chan func(), 1), recycleTime: nanotime() - int64(time.Hour)}
+	p.lock.Lock()
+	_ = p.workers.Insert(w)
+	// 模拟有一个调用者阻塞在Submit上
+	atomic.AddInt32(&p.blockingNum, 1)
+	p.lock.Unlock()
+
+	time.Sleep(3 * expiry)
+	assert.EqualValues(t, 1, p.Stats().Idle, "idle worker shouldn't be purged while callers are blocked")
+
+	atomic.AddInt32(&p.blockingNum, -1)
+	time.Sleep(3 * expiry)
+	assert.EqualValues(t, 0, p.Stats().Idle, "expired worker should be purged")
+	assert.Nil(t, <-w.task, "expired worker should be notified to stop")
+}
+
+func TestOnFull(t *testing.T) {
+	var fullCount int32
+	p, err := NewPool(1, WithNonblocking(true), WithPanicHandler(func(interface{}) {}),
+		WithOnFull(func() { atomic.AddInt32(&fullCount, 1) }))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	for round := 1; round <= 2; round++ {
+		ch := make(chan struct{})
+		exited := make(chan struct{})
+		// 任务结束的时候panic，让worker马上退出，pool重新变为不满
+		assert.NoError(t, p.Submit(func() {
+			<-ch
+			defer close(exited)
+			panic("exit")
+		}))
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, ErrPoolOverload, p.Submit(demoFunc))
+		}
+		time.Sleep(5 * onFullDebounce)
+		assert.EqualValues(t, round, atomic.LoadInt32(&fullCount), "OnFull should fire once per transition")
+		close(ch)
+		<-exited
+		for p.Running() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// 在去抖时间内重新变为不满，不会调用OnFull
+	p.markFull()
+	p.markNotFull()
+	time.Sleep(5 * onFullDebounce)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&fullCount), "OnFull should be debounced")
+}
+
+func TestSubmitInto(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	errTask := errors.New("task failed")
+	errc := make(chan error, 3)
+	assert.NoError(t, p.SubmitInto(func() error { return nil }, errc))
+	assert.NoError(t, p.SubmitInto(func() error { return errTask }, errc))
+	assert.NoError(t, p.SubmitInto(func() error { panic("Oops!") }, errc))
+
+	var results []error
+	for i := 0; i < 3; i++ {
+		results = append(results, <-errc)
+	}
+	assert.Contains(t, results, nil)
+	assert.Contains(t, results, errTask)
+	var panicked *PanicError
+	for _, err := range results {
+		if pe, ok := err.(*PanicError); ok {
+			panicked = pe
+		}
+	}
+	if assert.NotNil(t, panicked, "panic should be reported as *PanicError") {
+		assert.Equal(t, "Oops!", panicked.Value)
+	}
+	assert.EqualValues(t, 1, p.Panics(), "panic should be counted in Panics")
+	assert.EqualValues(t, 0, p.DroppedResults())
+
+	// 没有人接收的无缓冲channel，结果会被丢弃，worker不会一直阻塞
+	done := make(chan struct{})
+	assert.NoError(t, p.SubmitInto(func() error {
+		defer close(done)
+		return nil
+	}, make(chan error)))
+	<-done
+	for p.DroppedResults() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.EqualValues(t, 1, p.DroppedResults(), "result should be dropped")
+}
+
+func TestOverflowPool(t *testing.T) {
+	secondary, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer secondary.Release()
+	p, err := NewPool(1, WithNonblocking(true), WithOverflowPool(secondary))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	ch := make(chan struct{})
+	defer close(ch)
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, p.Submit(func() {
+			wg.Done()
+			<-ch
+		}), "overflowed task should be submitted to the secondary pool")
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, p.Running(), "spilled tasks shouldn't be counted by the primary pool")
+	assert.EqualValues(t, 3, secondary.Running(), "spilled tasks should run on the secondary pool")
+
+	secondary.Release()
+	assert.Equal(t, ErrPoolClosed, p.Submit(demoFunc), "primary should return the result of the secondary")
+}
+
+func TestOverflowPoolAllSubmitPaths(t *testing.T) {
+	secondary, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer secondary.Release()
+	p, err := NewPool(1, WithNonblocking(true), WithOverflowPool(secondary))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	// 占满主pool
+	p.incRunning()
+	defer p.decRunning()
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	task := func() { wg.Done() }
+	_, err = p.SubmitTracked(task)
+	assert.NoError(t, err, "SubmitTracked should spill into the secondary pool")
+	for _, err := range p.SubmitBatch([]func(){task, task}) {
+		assert.NoError(t, err, "SubmitBatch should spill into the secondary pool")
+	}
+	assert.NoError(t, p.SubmitNAtomic([]func(){task, task}), "SubmitNAtomic should spill into the secondary pool")
+	wg.Wait()
+}
+
+func TestTuneWakesBlockedSubmit(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	p1, err := NewPoolWithFunc(1, longRunningPoolFunc)
+	assert.NoErrorf(t, err, "create PoolWithFunc failed: %v", err)
+	defer p1.Release()
+
+	ch := make(chan struct{})
+	defer close(ch)
+	assert.NoError(t, p.Submit(func() { <-ch }))
+	assert.NoError(t, p1.Invoke(ch))
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_ = p.Submit(func() { <-ch })
+		done <- struct{}{}
+	}()
+	go func() {
+		_ = p1.Invoke(ch)
+		done <- struct{}{}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, done, 0, "submit should be blocked while the pool is full")
+
+	p.Tune(2)
+	p1.Tune(2)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("blocked submit should be woken up after increasing capacity")
+		}
+	}
+}
+
+func TestTuneWakesOnlyNeededWaiters(t *testing.T) {
+	p, err := NewPool(1, WithDisablePurge(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	p.incRunning()
+	defer p.decRunning()
+
+	const waiters = 100
+	workers := make(chan *goWorker, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			workers <- p.retrieveWorker(context.Background())
+		}()
+	}
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == waiters },
+		time.Second, 10*time.Millisecond)
+
+	// 扩容10个只唤醒10个等待者，其余的继续等待
+	p.Tune(11)
+	for i := 0; i < 10; i++ {
+		select {
+		case w := <-workers:
+			assert.NotNil(t, w)
+			defer func() { w.task <- nil }()
+		case <-time.After(time.Second):
+			t.Fatalf("blocked submit should be woken up after increasing capacity")
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, workers, 0)
+	assert.EqualValues(t, waiters-10, atomic.LoadInt32(&p.blockingNum))
+
+	// 所有的等待者最终都能拿到worker
+	p.Tune(waiters + 1)
+	for i := 0; i < waiters-10; i++ {
+		select {
+		case w := <-workers:
+			assert.NotNil(t, w)
+			defer func() { w.task <- nil }()
+		case <-time.After(time.Second):
+			t.Fatalf("all blocked submits should eventually proceed")
+		}
+	}
+}
+
+func TestWorkerLifecycleHooks(t *testing.T) {
+	type call struct {
+		workerID, goid uint64
+	}
+	started := make(chan call, 1)
+	stopped := make(chan call, 1)
+	p, err := NewPool(10,
+		WithOnWorkerStart(func(workerID uint64) {
+			started <- call{workerID, internal.GoID()}
+		}),
+		WithOnWorkerStop(func(workerID uint64) {
+			stopped <- call{workerID, internal.GoID()}
+			panic("stop hook panicked")
+		}),
+		WithPanicHandler(func(interface{}) {}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	goid := make(chan uint64, 1)
+	// 任务panic之后worker退出，触发OnWorkerStop，回调中的panic只记录日志
+	id, err := p.SubmitTracked(func() {
+		goid <- internal.GoID()
+		panic("task panicked")
+	})
+	assert.NoError(t, err)
+	taskGoid := <-goid
+	assert.Equal(t, call{id, taskGoid}, <-started, "OnWorkerStart should run in the worker goroutine")
+	assert.Equal(t, call{id, taskGoid}, <-stopped, "OnWorkerStop should run in the worker goroutine")
+
+	// worker仍然可以正常工作
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, p.Submit(wg.Done))
+	wg.Wait()
+	assert.NotEqual(t, id, (<-started).workerID)
+}
+
+func TestSubmitTracked(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var wg sync.WaitGroup
+	ids := make([]uint64, 2)
+	for i := range ids {
+		// pool只有一个worker，第二个任务会等到worker被归还之后复用它
+		wg.Add(1)
+		ids[i], err = p.SubmitTracked(wg.Done)
+		assert.NoError(t, err, "submit tracked task shouldn't return error")
+		wg.Wait()
+	}
+	assert.NotZero(t, ids[0])
+	assert.Equal(t, ids[0], ids[1], "reused worker should report the same id")
+
+	p1, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	ch := make(chan struct{})
+	defer close(ch)
+	id1, _ := p1.SubmitTracked(func() { <-ch })
+	id2, _ := p1.SubmitTracked(func() { <-ch })
+	assert.True(t, id2 > id1, "concurrent workers should have increasing ids")
+
+	p.Release()
+	_, err = p.SubmitTracked(demoFunc)
+	assert.Equal(t, ErrPoolClosed, err)
+}
+
+func TestSynchronousPool(t *testing.T) {
+	p, err := NewPool(0, WithSynchronous())
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	assert.EqualValues(t, 0, p.Cap())
+	assert.EqualValues(t, 0, p.Free())
+
+	caller := internal.GoID()
+	var taskGoID uint64
+	var running int
+	assert.NoError(t, p.Submit(func() {
+		taskGoID = internal.GoID()
+		running = p.Running()
+	}))
+	// Submit返回的时候任务已经执行完了
+	assert.Equal(t, caller, taskGoID, "task should run inline on the caller")
+	assert.EqualValues(t, 1, running, "inline task should be counted by Running")
+	assert.EqualValues(t, 0, p.Running())
+
+	var order []int
+	errs := p.SubmitBatch([]func(){func() { order = append(order, 1) }, func() { order = append(order, 2) }})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, []int{1, 2}, order)
+
+	p.Tune(10)
+	assert.EqualValues(t, 0, p.Cap(), "synchronous pool shouldn't be tuned")
+
+	p.Release()
+	assert.True(t, p.IsClosed())
+	assert.Equal(t, ErrPoolClosed, p.Submit(demoFunc))
+}
+
+func TestReleaseTimeout(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	ch := make(chan struct{})
+	defer close(ch)
+	_ = p.Submit(func() { <-ch })
+	assert.Equal(t, context.DeadlineExceeded, p.ReleaseTimeout(50*time.Millisecond),
+		"release should time out while a task is running")
+	assert.True(t, p.IsClosed())
+
+	p1, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	_ = p1.Submit(func() { <-ch })
+	assert.NoError(t, p1.ReleaseTimeout(0), "zero timeout should release immediately")
+	assert.True(t, p1.IsClosed())
+
+	p2, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	assert.NoError(t, p2.ReleaseTimeout(time.Second), "idle pool should be released in time")
+}
+
+func TestMaxTaskBufferBytes(t *testing.T) {
+	const size = 100000
+	// 预算连每个worker一个槽位都不够
+	p, err := NewPool(size, WithMaxTaskBufferBytes(size))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	assert.EqualValues(t, 0, p.taskChanCap, "channel cap should be reduced for a tight budget")
+	assert.EqualValues(t, 0, cap(p.workerCache.Get().(*goWorker).task), "new workers should use the reduced cap")
+
+	p1, err := NewPoolWithFunc(size, demoPoolFunc, WithMaxTaskBufferBytes(size))
+	assert.NoErrorf(t, err, "create PoolWithFunc failed: %v", err)
+	defer p1.Release()
+	assert.EqualValues(t, 0, p1.taskChanCap)
+
+	// 预算足够或者没有设置预算
+	p2, err := NewPool(size, WithMaxTaskBufferBytes(size*64))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p2.Release()
+	assert.EqualValues(t, workerChanCap, p2.taskChanCap)
+
+	assert.EqualValues(t, workerChanCap, taskChanCap(-1, 1, 8), "unlimited pool should ignore the budget")
+	assert.EqualValues(t, workerChanCap, taskChanCap(size, 0, 8))
+}
+
+func TestMigrateToShrink(t *testing.T) {
+	p, err := NewPool(8, WithPreAlloc(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	idle := make([]*goWorker, 4)
+	p.lock.Lock()
+	for i := range idle {
+		idle[i] = &goWorker{pool: p, task: make(chan func(), 1), recycleTime: nanotime() + int64(i)}
+		_ = p.workers.Insert(idle[i])
+	}
+	q := p.workers
+	p.lock.Unlock()
+
+	assert.NoError(t, p.MigrateTo(2))
+	assert.EqualValues(t, 2, p.Cap())
+	// 最早归还的两个worker被停止，队列原地缩小
+	for i, w := range idle {
+		if i < 2 {
+			assert.Nil(t, <-w.task, "oldest idle workers should be stopped")
+		} else {
+			assert.Len(t, w.task, 0, "recent idle workers should be kept")
+		}
+	}
+	p.lock.Lock()
+	assert.True(t, q == p.workers, "the loop queue should be shrunk in place")
+	assert.Len(t, p.workers.(*loopQueue).items, 2, "the loop queue should release the slots beyond the new size")
+	assert.EqualValues(t, 2, p.workers.Len())
+	p.lock.Unlock()
+}
+
+func TestShrink(t *testing.T) {
+	for _, preAlloc := range []bool{false, true} {
+		p, err := NewPool(10, WithPreAlloc(preAlloc))
+		assert.NoErrorf(t, err, "create Pool failed: %v", err)
+
+		// 6个空闲的worker，再加上2个正在运行任务的worker
+		idle := make([]*goWorker, 6)
+		p.lock.Lock()
+		for i := range idle {
+			idle[i] = &goWorker{pool: p, task: make(chan func(), 1), recycleTime: nanotime()}
+			_ = p.workers.Insert(idle[i])
+		}
+		p.lock.Unlock()
+		for i := 0; i < len(idle)+2; i++ {
+			p.incRunning()
+		}
+
+		assert.EqualValues(t, 0, p.Shrink(10), "shrinking to the current capacity should do nothing")
+		assert.EqualValues(t, 5, p.Shrink(3), "idle workers beyond the new size should be evicted")
+		assert.EqualValues(t, 3, p.Cap())
+		var stopped int
+		for _, w := range idle {
+			if len(w.task) > 0 {
+				assert.Nil(t, <-w.task, "evicted worker should be notified to stop")
+				stopped++
+			}
+		}
+		assert.EqualValues(t, 5, stopped)
+		p.lock.Lock()
+		assert.EqualValues(t, 1, p.workers.Len(), "one idle worker should be kept")
+		if preAlloc {
+			assert.Len(t, p.workers.(*loopQueue).items, 3, "the loop queue should release the slots beyond the new size")
+		}
+		p.lock.Unlock()
+
+		for i := 0; i < len(idle)+2; i++ {
+			p.decRunning()
+		}
+		p.Release()
+		assert.EqualValues(t, 0, p.Shrink(1), "released pool should not be shrunk")
+	}
+}
+
+func TestRebootWhenDrained(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	block := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, p.Submit(func() { <-block }))
+	}
+	p.Release()
+
+	assert.Equal(t, context.DeadlineExceeded, p.RebootWhenDrained(50*time.Millisecond), "pool with running workers should not reboot")
+	assert.True(t, p.IsClosed())
+	assert.Equal(t, ErrPoolClosed, p.Submit(func() {}))
+
+	close(block)
+	// worker执行完任务之后还要过一段时间才会退出，这里给足等待时间
+	assert.NoError(t, p.RebootWhenDrained(15*time.Second))
+	assert.False(t, p.IsClosed(), "drained pool should be rebooted")
+	assert.EqualValues(t, 0, p.Running())
+	assert.EqualValues(t, 0, atomic.LoadInt32(&p.alive), "all workers from the previous life should have exited")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, p.Submit(wg.Done), "rebooted pool should accept new tasks")
+	wg.Wait()
+}
+
+func TestSpawnExact(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	assert.Equal(t, ErrSpawnExceedsCap, p.SpawnExact(11))
+	assert.NoError(t, p.SpawnExact(6))
+	assert.EqualValues(t, 6, p.Running(), "exactly 6 workers should have been spawned")
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.SpawnExact(1))
+}
+
+func TestSpinWaitOnFull(t *testing.T) {
+	p, err := NewPool(1, WithSpinWaitOnFull(1000))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// pool已满，自旋结束之后应该回到阻塞等待，而不是一直空转
+	p.incRunning()
+	defer p.decRunning()
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.Submit(demoFunc)
+	}()
+	for atomic.LoadInt32(&p.blockingNum) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.revertWorker(w)
+	assert.NoError(t, <-submitted)
+	assert.NotNil(t, <-w.task, "blocked submitter should get the returned worker")
+}
+
+func TestMaxBlockingDuration(t *testing.T) {
+	p, err := NewPool(1, WithMaxBlockingDuration(50*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	p.incRunning()
+	defer p.decRunning()
+	start := time.Now()
+	assert.Equal(t, ErrPoolOverload, p.Submit(demoFunc), "blocked submitter should give up after the limit")
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	assert.EqualValues(t, 1, p.Stats().BlockingTimeouts)
+	assert.EqualValues(t, 0, p.Stats().Blocking)
+
+	// 在时限之内拿到了worker
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.revertWorker(&goWorker{pool: p, task: make(chan func(), 1)})
+	}()
+	assert.NoError(t, p.Submit(demoFunc))
+	assert.EqualValues(t, 1, p.Stats().BlockingTimeouts)
+}
+
+func TestErrorCategories(t *testing.T) {
+	for _, err := range []error{ErrInvalidPoolSize, ErrLackPoolFunc, ErrInvalidPoolExpiry, ErrInvalidPreAllocSize,
+		ErrInvalidPoolConfig, ErrSpawnExceedsCap, ErrInvalidChunkSize, ErrIncompatibleOptions, ErrCapacityFixed,
+		ErrCapacityBelowRunning, ErrInvalidMemEstimate, ErrUnknownClass, ErrNilWorkerArray, ErrNotSlice} {
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "%v should be an invalid argument error", err)
+		assert.False(t, errors.Is(err, ErrBusy))
+	}
+	assert.True(t, errors.Is(ErrPoolOverload, ErrBusy))
+	assert.False(t, errors.Is(ErrPoolOverload, ErrInvalidArgument))
+	assert.True(t, errors.Is(ErrInsufficientCapacity, ErrBusy))
+	assert.True(t, errors.Is(ErrSubmitTimeout, ErrBusy))
+	assert.True(t, errors.Is(ErrDelayQueueFull, ErrBusy))
+	for _, err := range []error{ErrPoolClosed, ErrContextCancelled, ErrUnhealthy, ErrTaskCancelled} {
+		assert.False(t, errors.Is(err, ErrBusy))
+		assert.False(t, errors.Is(err, ErrInvalidArgument))
+	}
+
+	// 被包装之后仍然可以匹配
+	wrapped := fmt.Errorf("submit order: %w", ErrPoolOverload)
+	assert.True(t, errors.Is(wrapped, ErrPoolOverload))
+	assert.True(t, errors.Is(wrapped, ErrBusy))
+	var pe *PoolError
+	assert.True(t, errors.As(wrapped, &pe))
+	assert.Equal(t, ErrPoolOverload, pe)
+	assert.False(t, errors.As(errors.New("task failed"), &pe), "task errors are not pool errors")
+
+	// 实际返回的错误
+	p, err := NewPool(1, WithNonblocking(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	p.incRunning()
+	defer p.decRunning()
+	assert.True(t, errors.Is(p.Submit(demoFunc), ErrBusy), "nonblocking overload should be busy")
+	_, err = NewPoolWithFunc(-1, demoPoolFunc)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+}
+
+func TestRebootIdempotent(t *testing.T) {
+	p, err := NewPool(10, WithExpiryDuration(10*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	assert.False(t, p.Reboot(), "rebooting an open pool should be a no-op")
+
+	time.Sleep(50 * time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.Release()
+		}()
+		go func() {
+			defer wg.Done()
+			p.Reboot()
+		}()
+	}
+	wg.Wait()
+	p.Reboot()
+	assert.False(t, p.IsClosed())
+	assert.NoError(t, p.ShutdownContext().Err(), "rebooted pool should have a live shutdown context")
+
+	// 旧的清理goroutine在下一次运行时发现pool重启过就会退出
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, runtime.NumGoroutine() <= before, "purge goroutines leaked: %d -> %d", before, runtime.NumGoroutine())
+}
+
+func TestRebootWith(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	assert.Equal(t, ErrRebootNotReady, p.RebootWith(WithCapacity(20)), "rebooting an open pool should fail")
+
+	assert.NoError(t, p.ReleaseContext(context.Background()))
+	assert.Equal(t, ErrIncompatibleOptions, p.RebootWith(WithPreAlloc(true)))
+	assert.Equal(t, ErrInvalidPoolExpiry, p.RebootWith(WithCapacity(20), WithExpiryDuration(-1)))
+	assert.True(t, p.IsClosed(), "pool should stay closed when the new options are invalid")
+	assert.EqualValues(t, 10, p.Cap())
+
+	assert.NoError(t, p.RebootWith(WithCapacity(20), WithNonblocking(true)))
+	assert.False(t, p.IsClosed())
+	assert.EqualValues(t, 20, p.Cap())
+	assert.EqualValues(t, DefaultCleanIntervalTime, p.options.ExpiryDuration, "unchanged options should be kept")
+
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, p.Submit(func() { <-block }))
+	}
+	assert.Equal(t, ErrPoolOverload, p.Submit(demoFunc), "the new capacity and Nonblocking should be in effect")
+}
+
+func TestPanicStormGuard(t *testing.T) {
+	const window = 200 * time.Millisecond
+	p, err := NewPool(100, WithPanicStormGuard(5, window), WithPanicHandler(func(interface{}) {}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, p.Submit(func() { panic("boom") }))
+	}
+	for p.Panics() < 6 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, ErrPanicStorm, p.Submit(demoFunc), "guard should trip after too many panics")
+	assert.True(t, errors.Is(ErrPanicStorm, ErrBusy))
+	_, err = p.SubmitTracked(demoFunc)
+	assert.Equal(t, ErrPanicStorm, err)
+
+	time.Sleep(window + 50*time.Millisecond)
+	assert.NoError(t, p.Submit(demoFunc), "guard should recover after the window")
+
+	// 没有触发阈值的时候不会拒绝
+	p1, err := NewPool(100, WithPanicStormGuard(5, time.Minute), WithPanicHandler(func(interface{}) {}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, p1.Submit(func() { panic("boom") }))
+	}
+	for p1.Panics() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, p1.Submit(demoFunc))
+}
+
+func TestSubmitWithPanicHandler(t *testing.T) {
+	var global, local int32
+	p, err := NewPool(10, WithPanicHandler(func(interface{}) {
+		atomic.AddInt32(&global, 1)
+	}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	done := make(chan interface{}, 1)
+	err = p.SubmitWithPanicHandler(func() { panic("local") }, func(r interface{}) {
+		atomic.AddInt32(&local, 1)
+		done <- r
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "local", <-done)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&global), "global handler should be overridden")
+	assert.EqualValues(t, 1, p.Panics())
+
+	// handler为nil的时候使用全局的handler
+	assert.NoError(t, p.SubmitWithPanicHandler(func() { panic("global") }, nil))
+	for p.Panics() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	for atomic.LoadInt32(&global) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&local))
+}
+
+func TestOnSaturatedAndRecovered(t *testing.T) {
+	var saturated, recovered int32
+	p, err := NewPool(3, WithOnSaturated(func() {
+		atomic.AddInt32(&saturated, 1)
+	}), WithOnRecovered(func() {
+		atomic.AddInt32(&recovered, 1)
+	}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var started sync.WaitGroup
+	blocks := make([]chan struct{}, 3)
+	finished := make(chan struct{}, 3)
+	for i := range blocks {
+		block := make(chan struct{})
+		blocks[i] = block
+		started.Add(1)
+		assert.NoError(t, p.Submit(func() {
+			started.Done()
+			<-block
+			finished <- struct{}{}
+		}))
+	}
+	started.Wait()
+	for atomic.LoadInt32(&saturated) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.EqualValues(t, 0, atomic.LoadInt32(&recovered))
+
+	close(blocks[0])
+	<-finished
+	for atomic.LoadInt32(&recovered) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 没有再次饱和，之后的worker空闲下来也不会再触发
+	close(blocks[1])
+	close(blocks[2])
+	<-finished
+	<-finished
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&saturated), "OnSaturated should fire once")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&recovered), "OnRecovered should fire once")
+}
+
+func TestSubmitWithCallback(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var ran, calls int32
+	done := make(chan interface{}, 2)
+	err = p.SubmitWithCallback(func() {
+		atomic.AddInt32(&ran, 1)
+	}, func(recovered interface{}) {
+		atomic.AddInt32(&calls, 1)
+		done <- recovered
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, <-done, "successful task should report nil")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&ran))
+
+	err = p.SubmitWithCallback(func() {
+		panic("oops")
+	}, func(recovered interface{}) {
+		atomic.AddInt32(&calls, 1)
+		done <- recovered
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "oops", <-done, "panicking task should report the recovered value")
+	assert.EqualValues(t, 1, p.Panics())
+
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls), "done should run exactly once per task")
+}
+
+func TestNoReuse(t *testing.T) {
+	const size = 5
+	p, err := NewPool(size, WithNoReuse(true), WithNonblocking(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	ids := make(map[uint64]bool)
+	for i := 0; i < size; i++ {
+		done := make(chan struct{})
+		id, err := p.SubmitTracked(func() { close(done) })
+		assert.NoError(t, err)
+		<-done
+		assert.False(t, ids[id], "task should run on a brand-new worker")
+		ids[id] = true
+	}
+	assert.Len(t, ids, size)
+	assert.True(t, p.Running() <= size, "pool should respect its capacity")
+
+	p1, err := NewPool(size, WithNoReuse(true), WithNonblocking(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < size; i++ {
+		assert.NoError(t, p1.Submit(func() { <-block }))
+	}
+	assert.Equal(t, ErrPoolOverload, p1.Submit(demoFunc), "pool should respect its capacity")
+}
+
+func TestSelfTest(t *testing.T) {
+	for _, preAlloc := range []bool{false, true} {
+		p, err := NewPool(10, WithPreAlloc(preAlloc))
+		assert.NoErrorf(t, err, "create Pool failed: %v", err)
+
+		idle := make([]*goWorker, 3)
+		p.lock.Lock()
+		for i := range idle {
+			idle[i] = &goWorker{pool: p, task: make(chan func(), 1), recycleTime: nanotime()}
+			_ = p.workers.Insert(idle[i])
+		}
+		p.lock.Unlock()
+		for range idle {
+			p.incRunning()
+		}
+		assert.Empty(t, p.SelfTest(), "healthy pool should have no anomalies")
+
+		// 迁移之后仍然按recycleTime排列
+		_, err = p.SwapWorkerArray(NewWorkerArray(StackType, 0))
+		assert.NoError(t, err)
+		assert.Empty(t, p.SelfTest(), "swapping the worker array should keep the order")
+
+		p.lock.Lock()
+		_ = p.workers.Insert(&goWorker{pool: p, task: make(chan func(), 1), recycleTime: idle[0].recycleTime - 1})
+		_ = p.workers.Insert(nil)
+		p.lock.Unlock()
+		assert.Len(t, p.SelfTest(), 3, "out of order recycleTime, nil worker and idle count should be reported")
+
+		p.lock.Lock()
+		for p.workers.Detach() != nil {
+		}
+		p.lock.Unlock()
+		for range idle {
+			p.decRunning()
+		}
+		p.Release()
+	}
+}
+
+func TestFreeUnlimited(t *testing.T) {
+	p, err := NewPool(-1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	assert.True(t, p.IsUnlimited())
+	assert.True(t, p.Free() > 0, "unlimited pool should always have free capacity")
+	assert.Equal(t, p.Free(), p.Stats().Free)
+
+	p1, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	assert.False(t, p1.IsUnlimited())
+	assert.Equal(t, 10, p1.Free())
+
+	// 合计的时候不会溢出
+	total := p1.Stats().add(p.Stats()).add(p1.Stats())
+	assert.EqualValues(t, -1, total.Capacity)
+	assert.Equal(t, p.Free(), total.Free)
+}
+
+func TestTrySubmitFast(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// pool已满的时候即使没有设置Nonblocking也不会阻塞
+	p.incRunning()
+	assert.False(t, p.TrySubmitFast(demoFunc))
+	allocs := testing.AllocsPerRun(100, func() {
+		p.TrySubmitFast(demoFunc)
+	})
+	assert.Zero(t, allocs, "rejecting a task should not allocate")
+	p.decRunning()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.True(t, p.TrySubmitFast(wg.Done))
+	wg.Wait()
+
+	p.Release()
+	assert.False(t, p.TrySubmitFast(demoFunc))
+}
+
+func TestInitialWorkers(t *testing.T) {
+	for _, hot := range []bool{false, true} {
+		p, err := NewPool(10, WithInitialWorkers(4), WithHotWorker(hot))
+		assert.NoErrorf(t, err, "create Pool failed: %v", err)
+		assert.Equal(t, 4, p.LenIdle(), "initial workers should be idle right after construction")
+		assert.Equal(t, 4, p.Running())
+		assert.Equal(t, 10, p.Cap())
+
+		// 可以增长到容量
+		block := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, p.Submit(func() { <-block }))
+		}
+		assert.Equal(t, 0, p.LenIdle())
+		assert.Equal(t, 10, p.Running())
+		close(block)
+		p.Release()
+	}
+
+	p, err := NewPool(-1, WithInitialWorkers(3))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	assert.Equal(t, 3, p.LenIdle())
+	p.Release()
+
+	_, err = NewPool(2, WithInitialWorkers(3))
+	assert.Equal(t, ErrSpawnExceedsCap, err)
+	_, err = NewPool(2, WithInitialWorkers(-1))
+	assert.Equal(t, ErrInvalidPoolConfig, err)
+}
+
+func TestAdjustCapacity(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	assert.NoError(t, p.AdjustCapacity(10))
+	assert.Equal(t, 20, p.Cap())
+	assert.NoError(t, p.AdjustCapacity(-5))
+	assert.Equal(t, 15, p.Cap())
+	assert.Equal(t, ErrInvalidPoolSize, p.AdjustCapacity(-15))
+
+	for i := 0; i < 5; i++ {
+		p.incRunning()
+		defer p.decRunning()
+	}
+	assert.Equal(t, ErrCapacityBelowRunning, p.AdjustCapacity(-11))
+	assert.Equal(t, 15, p.Cap(), "capacity should not change on error")
+	assert.NoError(t, p.AdjustCapacity(-10))
+	assert.Equal(t, 5, p.Cap())
+
+	// 并发调整不会丢失增量
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, p.AdjustCapacity(1))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 55, p.Cap())
+
+	p1, err := NewPool(10, WithPreAlloc(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	assert.Equal(t, ErrCapacityFixed, p1.AdjustCapacity(1))
+	p2, err := NewPool(-1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p2.Release()
+	assert.Equal(t, ErrCapacityFixed, p2.AdjustCapacity(1))
+}
+
+func TestClone(t *testing.T) {
+	var handled int32
+	p, err := NewPool(10, WithNonblocking(true), WithExpiryDuration(time.Minute), WithMaxBlockingTasks(5),
+		WithPanicHandler(func(interface{}) { atomic.AddInt32(&handled, 1) }))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	p.Tune(20)
+
+	c, err := p.Clone()
+	assert.NoErrorf(t, err, "clone Pool failed: %v", err)
+	defer c.Release()
+	assert.Equal(t, 20, c.Cap(), "clone should use the current capacity")
+	assert.True(t, c.options.Nonblocking)
+	assert.Equal(t, time.Minute, c.options.ExpiryDuration)
+	assert.Equal(t, 5, c.options.MaxBlockingTasks)
+	assert.Equal(t, p.options.Logger, c.options.Logger, "logger should be shared")
+	assert.False(t, p.options == c.options, "options should be copied")
+
+	// 回调是共享的
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, c.Submit(func() {
+		defer wg.Done()
+		panic("Oops!")
+	}))
+	wg.Wait()
+	eventually(t, func() bool { return atomic.LoadInt32(&handled) == 1 }, time.Second, 10*time.Millisecond)
+
+	// 状态是独立的
+	assert.Zero(t, p.Running())
+	assert.EqualValues(t, 1, c.Panics())
+	assert.Zero(t, p.Panics())
+	p.Release()
+	assert.False(t, c.IsClosed())
+	c2, err := p.Clone()
+	assert.NoErrorf(t, err, "clone Pool failed: %v", err)
+	assert.False(t, c2.IsClosed(), "clone of a closed pool should be open")
+	c2.Release()
+}
+
+func TestInFlight(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, p.Submit(wg.Done))
+	wg.Wait()
+	eventually(t, func() bool { return p.InFlight() == 0 }, time.Second, 10*time.Millisecond,
+		"in-flight count should drop to zero after the task completes")
+
+	// 带缓冲的任务channel中可以排着还没有开始的任务，这时InFlight会超过Running
+	p, err = NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	w := &goWorker{pool: p, task: make(chan func(), 2)}
+	p.incRunning()
+	defer p.decRunning()
+	w.dispatch(demoFunc)
+	w.dispatch(demoFunc)
+	assert.Equal(t, 2, p.InFlight())
+	assert.Equal(t, 1, p.Running())
+
+	// 和worker的执行循环一样执行完之后回到零
+	for len(w.task) > 0 {
+		f := <-w.task
+		p.taskStarted()
+		f()
+		p.taskFinished()
+	}
+	assert.Equal(t, 0, p.InFlight())
+}
+
+func TestSubmitIfNotFull(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	for i := 0; i < 7; i++ {
+		p.incRunning()
+		defer p.decRunning()
+	}
+	assert.Equal(t, ErrPoolOverload, p.SubmitIfNotFull(3, demoFunc), "should yield when free slots are not above threshold")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, p.SubmitIfNotFull(2, wg.Done))
+	wg.Wait()
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.SubmitIfNotFull(0, demoFunc))
+}
+
+func TestSubmitNAtomic(t *testing.T) {
+	p, err := NewPool(4)
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.NoError(t, p.SubmitNAtomic(nil))
+
+	// 一个空闲的worker加上还能创建的3个
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.incRunning()
+	assert.True(t, p.revertWorker(w))
+	release := make(chan struct{})
+	var ran int32
+	task := func() {
+		atomic.AddInt32(&ran, 1)
+		<-release
+	}
+
+	// 容量不够的时候一个都不提交
+	assert.Equal(t, ErrInsufficientCapacity, p.SubmitNAtomic([]func(){task, task, task, task, task}))
+	assert.EqualValues(t, 1, p.Running())
+	assert.EqualValues(t, 1, p.LenIdle())
+	assert.EqualValues(t, 0, p.InFlight())
+
+	assert.NoError(t, p.SubmitNAtomic([]func(){task, task, task, task}))
+	assert.EqualValues(t, 4, p.Running())
+	assert.EqualValues(t, 0, p.LenIdle())
+	assert.EqualValues(t, 4, p.InFlight())
+	go (<-w.task)()
+	eventually(t, func() bool { return atomic.LoadInt32(&ran) == 4 }, time.Second, time.Millisecond)
+	assert.Equal(t, ErrInsufficientCapacity, p.SubmitNAtomic([]func(){demoFunc}))
+	close(release)
+	p.decRunning()
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.SubmitNAtomic([]func(){demoFunc}))
+}
+
+func TestWaitForCapacity(t *testing.T) {
+	p, err := NewPool(2)
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.NoError(t, p.WaitForCapacity(2, time.Millisecond))
+	assert.Equal(t, ErrInsufficientCapacity, p.WaitForCapacity(3, time.Second))
+
+	// pool已满，没有空闲的worker
+	p.incRunning()
+	p.incRunning()
+	defer p.decRunning()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, p.WaitForCapacity(1, 20*time.Millisecond))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	// 归还一个worker之后被唤醒
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.revertWorker(&goWorker{pool: p, task: make(chan func(), 1)})
+	}()
+	assert.NoError(t, p.WaitForCapacity(1, time.Second))
+	assert.Equal(t, context.DeadlineExceeded, p.WaitForCapacity(2, 10*time.Millisecond))
+
+	// 扩容之后被唤醒
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, p.AdjustCapacity(1))
+	}()
+	assert.NoError(t, p.WaitForCapacity(2, time.Second))
+
+	// pool关闭的时候被唤醒
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Release()
+	}()
+	assert.Equal(t, ErrPoolClosed, p.WaitForCapacity(3, time.Second))
+	p.decRunning()
+}
+
+func TestCancelPending(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.Equal(t, 0, p.CancelPending())
+
+	p.incRunning()
+	defer p.decRunning()
+	var ran int32
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- p.Submit(func() { atomic.AddInt32(&ran, 1) })
+		}()
+	}
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, p.CancelPending())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ErrTaskCancelled, <-errs)
+	}
+	assert.EqualValues(t, 0, atomic.LoadInt32(&p.blockingNum))
+	assert.EqualValues(t, 0, atomic.LoadInt32(&ran), "cancelled tasks should not run")
+
+	// 之后的提交不受影响
+	go func() {
+		errs <- p.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}()
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 1 }, time.Second, time.Millisecond)
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.revertWorker(w)
+	assert.NoError(t, <-errs)
+	(<-w.task)()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&ran))
+}
+
+func TestSetNonblocking(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	p.incRunning()
+	defer p.decRunning()
+	errs := make(chan error, 4)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- p.Submit(demoFunc)
+		}()
+	}
+	go func() {
+		errs <- p.SubmitWithPriority(1, demoFunc)
+	}()
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 4 }, time.Second, time.Millisecond)
+
+	// 切换成非阻塞之后，阻塞的提交者都被释放
+	p.SetNonblocking(true)
+	for i := 0; i < 4; i++ {
+		select {
+		case err := <-errs:
+			assert.Equal(t, ErrPoolOverload, err)
+		case <-time.After(time.Second):
+			t.Fatal("blocked submitters should be released")
+		}
+	}
+	assert.EqualValues(t, 0, atomic.LoadInt32(&p.blockingNum))
+	assert.Equal(t, ErrPoolOverload, p.Submit(demoFunc))
+	assert.True(t, ConfigOf(p).Nonblocking)
+
+	// 切换回阻塞
+	p.SetNonblocking(false)
+	go func() {
+		errs <- p.Submit(demoFunc)
+	}()
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 1 }, time.Second, time.Millisecond)
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.revertWorker(w)
+	assert.NoError(t, <-errs)
+}
+
+func TestReleaseGraceful(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoError(t, err)
+
+	// 三个worker先后退出
+	for i := 1; i <= 3; i++ {
+		p.incRunning()
+		go func(d time.Duration) {
+			time.Sleep(d)
+			p.decRunning()
+		}(time.Duration(i) * 30 * time.Millisecond)
+	}
+	var reports []int
+	assert.NoError(t, p.ReleaseGraceful(context.Background(), func(remaining int) {
+		reports = append(reports, remaining)
+	}))
+	assert.Equal(t, []int{3, 2, 1, 0}, reports)
+	assert.True(t, p.IsClosed())
+
+	// ctx结束的时候还没有退出完
+	p1, err := NewPool(10)
+	assert.NoError(t, err)
+	p1.incRunning()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	reports = nil
+	assert.Equal(t, context.DeadlineExceeded, p1.ReleaseGraceful(ctx, func(remaining int) {
+		reports = append(reports, remaining)
+	}))
+	assert.Equal(t, []int{1}, reports)
+	p1.decRunning()
+	assert.NoError(t, p1.ReleaseGraceful(context.Background(), nil))
+}
+
+func TestSubmitTimeout(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	// 有容量的时候马上提交
+	done := make(chan struct{})
+	assert.NoError(t, p.SubmitTimeout(time.Millisecond, func() { close(done) }))
+	<-done
+
+	// pool已满，超时之内没有worker，p中的worker还没有回到pool，换一个pool
+	p1, err := NewPool(1)
+	assert.NoError(t, err)
+	defer p1.Release()
+	p1.incRunning()
+	defer p1.decRunning()
+	start := time.Now()
+	assert.Equal(t, ErrSubmitTimeout, p1.SubmitTimeout(30*time.Millisecond, demoFunc))
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&p1.blockingNum))
+
+	// 超时之前有worker归还
+	w := &goWorker{pool: p1, task: make(chan func(), 1)}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p1.revertWorker(w)
+	}()
+	var ran bool
+	assert.NoError(t, p1.SubmitTimeout(time.Second, func() { ran = true }))
+	(<-w.task)()
+	assert.True(t, ran)
+}
+
+func TestWarmWorkerCache(t *testing.T) {
+	_, err := NewPool(10, WithWarmWorkerCache(-1))
+	assert.EqualError(t, err, ErrInvalidPoolConfig.Error())
+
+	const n = 100
+	p, err := NewPool(n, WithPreAlloc(true), WithWarmWorkerCache(n))
+	assert.NoError(t, err)
+	defer p.Release()
+	var news int32
+	newWorker := p.workerCache.New
+	p.workerCache.New = func() interface{} {
+		atomic.AddInt32(&news, 1)
+		return newWorker()
+	}
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		w := p.retrieveWorker(ctx)
+		defer func() { w.task <- nil }()
+	}
+	// 开启race检测的时候sync.Pool会随机丢弃Put的对象，所以不要求全部都来自预热的缓存
+	assert.True(t, atomic.LoadInt32(&news) < n/2, "warmed cache should serve workers, New called %d times", news)
+}
+
+func TestWithWorker(t *testing.T) {
+	p, err := NewPool(2)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	var ran bool
+	assert.NoError(t, p.WithWorker(func() { ran = true }), "fn should run synchronously")
+	assert.True(t, ran)
+	eventually(t, func() bool { return p.InFlight() == 0 }, time.Second, time.Millisecond)
+
+	err = p.WithWorker(func() { panic("boom") })
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "boom", pe.Value)
+	assert.EqualValues(t, 1, p.Panics())
+	// panic之后worker没有退出，任务也结束了，名额没有泄漏
+	eventually(t, func() bool { return p.InFlight() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, p.Running())
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.WithWorker(func() {}))
+}
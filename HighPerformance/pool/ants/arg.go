@@ -0,0 +1,44 @@
+package ants
+
+import "sync"
+
+// argTask 把一个参数和处理它的函数包装成一个任务，run是绑定好的exec，复用argTask的时候不需要再创建闭包
+type argTask struct {
+	arg interface{}
+	f   func(interface{})
+	run func()
+}
+
+// argTaskPool 缓存argTask，exec会用到它，所以New要在init中设置
+var argTaskPool sync.Pool
+
+func init() {
+	argTaskPool.New = func() interface{} {
+		t := new(argTask)
+		t.run = t.exec
+		return t
+	}
+}
+
+func (t *argTask) exec() {
+	f, arg := t.f, t.arg
+	// 执行之前就放回去，f发生panic的时候也不会丢掉
+	t.f, t.arg = nil, nil
+	argTaskPool.Put(t)
+	f(arg)
+}
+
+// Go 把arg和f包装成一个任务提交到p中，任务执行时调用f(arg)，不需要为了带一个参数单独创建一个PoolWithFunc。
+// 包装任务用的对象会被复用，所以除了把arg转换成interface{}之外（arg是指针或者不超过一个字长的时候通常不会分配内存）
+// 不会像直接提交闭包那样每次都分配内存；参数类型固定的时候PoolWithFunc仍然是开销最小的。
+// 模块要兼容go1.14，不能使用泛型，f的参数是interface{}，需要在f中自己做类型断言
+func Go(p *Pool, arg interface{}, f func(interface{})) error {
+	t := argTaskPool.Get().(*argTask)
+	t.arg, t.f = arg, f
+	if err := p.Submit(t.run); err != nil {
+		t.arg, t.f = nil, nil
+		argTaskPool.Put(t)
+		return err
+	}
+	return nil
+}
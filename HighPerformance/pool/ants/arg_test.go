@@ -0,0 +1,72 @@
+package ants
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGo(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sum int
+	)
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		err = Go(p, i, func(arg interface{}) {
+			defer wg.Done()
+			mu.Lock()
+			sum += arg.(int)
+			mu.Unlock()
+		})
+		assert.NoError(t, err)
+	}
+	wg.Wait()
+	assert.EqualValues(t, 15, sum)
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, Go(p, 1, func(interface{}) {
+		t.Fatal("task should not run on a closed pool")
+	}))
+}
+
+type benchArg struct {
+	n int
+}
+
+func consumeArg(arg interface{}) {
+	arg.(*benchArg).n++
+}
+
+// 同步的pool在调用者的goroutine中直接执行任务，可以单独比较两种提交方式的内存分配
+func BenchmarkGo(b *testing.B) {
+	p, _ := NewPool(0, WithSynchronous())
+	defer p.Release()
+	arg := &benchArg{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Go(p, arg, consumeArg)
+	}
+}
+
+func BenchmarkSubmitClosure(b *testing.B) {
+	p, _ := NewPool(0, WithSynchronous())
+	defer p.Release()
+	arg := &benchArg{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a := &benchArg{n: arg.n}
+		_ = p.Submit(func() {
+			consumeArg(a)
+			arg.n = a.n
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package ants
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// AutoScale 是pool自动扩缩容的配置，扩缩容在清理goroutine中进行，关闭了定期清理的话不会生效
+type AutoScale struct {
+	// 容量的下限和上限
+	Min, Max int
+
+	// 阻塞在Submit上的调用者不少于ScaleUpThreshold的时候，容量翻倍，不超过Max
+	ScaleUpThreshold int
+
+	// Running()持续低于Min超过Cooldown的时候，容量减半，不低于Min
+	Cooldown time.Duration
+}
+
+// validate 校验自动扩缩容的配置
+func (as *AutoScale) validate() error {
+	if as.Min <= 0 || as.Max < as.Min || as.ScaleUpThreshold <= 0 || as.Cooldown < 0 {
+		return ErrInvalidPoolConfig
+	}
+	return nil
+}
+
+// autoScale 根据阻塞的调用者数量和运行的goroutine数量调整容量，只在清理goroutine中调用
+func (p *Pool) autoScale() {
+	as := p.options.AutoScale
+	capacity := p.Cap()
+	if as == nil || capacity == -1 || p.IsClosed() {
+		return
+	}
+
+	if int(atomic.LoadInt32(&p.blockingNum)) >= as.ScaleUpThreshold && capacity < as.Max {
+		p.lowSince = time.Time{}
+		newCap := capacity * 2
+		if newCap > as.Max {
+			newCap = as.Max
+		}
+		p.scaleTo(capacity, newCap, EventScaleUp)
+		return
+	}
+
+	if p.Running() >= as.Min {
+		p.lowSince = time.Time{}
+		return
+	}
+	if p.lowSince.IsZero() {
+		p.lowSince = time.Now()
+		return
+	}
+	if time.Since(p.lowSince) > as.Cooldown && capacity > as.Min {
+		// 再次缩容需要再等待一个Cooldown
+		p.lowSince = time.Now()
+		newCap := capacity / 2
+		if newCap < as.Min {
+			newCap = as.Min
+		}
+		p.scaleTo(capacity, newCap, EventScaleDown)
+	}
+}
+
+// scaleTo 修改容量并发出事件
+func (p *Pool) scaleTo(oldCap, newCap int, t EventType) {
+	if err := p.MigrateTo(newCap); err != nil {
+		return
+	}
+	p.emit(Event{Type: t, OldCap: oldCap, NewCap: newCap})
+}
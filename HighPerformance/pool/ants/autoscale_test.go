@@ -0,0 +1,55 @@
+package ants
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoScaleUp(t *testing.T) {
+	p, err := NewPool(2, WithExpiryDuration(20*time.Millisecond), WithAutoScale(1, 3, 1, time.Hour))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	ch := make(chan struct{})
+	defer close(ch)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, p.Submit(func() { <-ch }))
+	}
+	done := make(chan struct{})
+	go func() {
+		// 阻塞在Submit上，触发扩容
+		_ = p.Submit(func() { <-ch })
+		close(done)
+	}()
+
+	select {
+	case e := <-p.Watch():
+		assert.Equal(t, Event{Type: EventScaleUp, Time: e.Time, OldCap: 2, NewCap: 3}, e)
+	case <-time.After(time.Second):
+		t.Fatalf("scale up event should be emitted")
+	}
+	<-done
+	assert.EqualValues(t, 3, p.Cap(), "capacity should be doubled up to max")
+}
+
+func TestAutoScaleDown(t *testing.T) {
+	p, err := NewPool(8, WithExpiryDuration(20*time.Millisecond), WithAutoScale(3, 8, 1, 50*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	for _, newCap := range []int{4, 3} {
+		select {
+		case e := <-p.Watch():
+			assert.Equal(t, EventScaleDown, e.Type)
+			assert.EqualValues(t, newCap, e.NewCap, "capacity should be halved down to min")
+		case <-time.After(time.Second):
+			t.Fatalf("scale down event should be emitted")
+		}
+	}
+	assert.EqualValues(t, 3, p.Cap())
+
+	_, err = NewPool(8, WithAutoScale(4, 2, 1, time.Second))
+	assert.Equal(t, ErrInvalidPoolConfig, err)
+}
@@ -0,0 +1,19 @@
+package ants
+
+import "context"
+
+// ignoreBlockingLimitKey 标记一次提交不受MaxBlockingTasks的限制
+type ignoreBlockingLimitKey struct{}
+
+// ignoreBlockingLimit ctx是否带有ignoreBlockingLimitKey标记
+func ignoreBlockingLimit(ctx context.Context) bool {
+	ignore, _ := ctx.Value(ignoreBlockingLimitKey{}).(bool)
+	return ignore
+}
+
+// SubmitOrBlock 和Submit一样提交一个任务，但是不受MaxBlockingTasks的限制，pool已满的时候一直阻塞到有可用的worker，
+// 适合不能被丢弃的关键任务。阻塞中的SubmitOrBlock同样计入阻塞的提交者数量，会让普通的Submit更早地返回ErrPoolOverload。
+// 非阻塞的pool仍然直接返回ErrPoolOverload（或者转交给OverflowPool），其余的错误和Submit相同
+func (p *Pool) SubmitOrBlock(task func()) error {
+	return p.submit(context.WithValue(context.Background(), ignoreBlockingLimitKey{}, true), task)
+}
@@ -0,0 +1,34 @@
+package ants
+
+import "sync/atomic"
+
+// SubmitBroadcast 把task同时交给当前所有空闲的worker，每个空闲的worker都会执行一次，
+// 适合让每个worker goroutine都处理一次的场景，比如让缓存失效或者重新加载配置。
+// 正在执行任务的worker和之后新创建的worker不会执行它，没有空闲worker的时候task不会被执行；
+// 任务channel已经满了（无缓冲的channel上worker还没有开始接收）的worker会被跳过并放回空闲队列。pool已经关闭的时候返回ErrPoolClosed
+func (p *Pool) SubmitBroadcast(task func()) error {
+	if p.IsClosed() {
+		return ErrPoolClosed
+	}
+	// 先把空闲的worker都取出来，它们执行完之后会像普通任务一样自己回到空闲队列中
+	p.lock.Lock()
+	idleWorkers := make([]*goWorker, 0, p.workers.Len()+1)
+	for w := p.detachWorker(); w != nil; w = p.detachWorker() {
+		idleWorkers = append(idleWorkers, w)
+	}
+	p.lock.Unlock()
+
+	for _, w := range idleWorkers {
+		atomic.AddInt32(&p.inFlight, 1)
+		select {
+		case w.task <- task:
+		default:
+			atomic.AddInt32(&p.inFlight, -1)
+			// 没有收到任务的worker不会自己回到空闲队列，需要放回去，放不回去的话让它退出
+			if !p.revertWorker(w) {
+				w.task <- nil
+			}
+		}
+	}
+	return nil
+}
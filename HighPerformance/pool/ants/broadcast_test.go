@@ -0,0 +1,75 @@
+package ants
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitBroadcast(t *testing.T) {
+	p, err := NewPool(10, WithHotWorker(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	idle := make([]*goWorker, 3)
+	for i := range idle {
+		idle[i] = &goWorker{pool: p, task: make(chan func(), 1)}
+		p.incRunning()
+		defer p.decRunning()
+		assert.True(t, p.revertWorker(idle[i]))
+	}
+	// 任务channel已经满了的worker会被跳过
+	full := &goWorker{pool: p, task: make(chan func(), 1)}
+	full.task <- demoFunc
+	p.incRunning()
+	defer p.decRunning()
+	assert.True(t, p.revertWorker(full))
+
+	var n int32
+	assert.NoError(t, p.SubmitBroadcast(func() { atomic.AddInt32(&n, 1) }))
+	for _, w := range idle {
+		(<-w.task)()
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&n), "every idle worker should receive the task")
+	assert.Len(t, full.task, 1)
+	p.lock.Lock()
+	assert.Equal(t, full, p.detachWorker(), "skipped worker should be back in the idle queue")
+	assert.Nil(t, p.detachWorker(), "idle workers should have been detached")
+	p.lock.Unlock()
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.SubmitBroadcast(demoFunc))
+}
+
+func TestSubmitBroadcastUnbufferedSkip(t *testing.T) {
+	p, err := NewPool(4)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 无缓冲的任务channel上还没有开始接收的worker会走到default分支
+	w := &goWorker{pool: p, task: make(chan func())}
+	p.incRunning()
+	assert.True(t, p.revertWorker(w))
+	assert.Equal(t, 3, p.Free())
+
+	assert.NoError(t, p.SubmitBroadcast(demoFunc))
+	assert.Equal(t, 1, p.LenIdle(), "skipped worker should be back in the idle queue")
+
+	// 模拟worker收到nil之后退出，关闭pool的时候它应该被通知到并归还占用的容量
+	exited := make(chan struct{})
+	go func() {
+		if f := <-w.task; f == nil {
+			p.decRunning()
+		}
+		close(exited)
+	}()
+	p.Release()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("skipped worker was leaked and never told to exit")
+	}
+	assert.Equal(t, 4, p.Free(), "capacity held by the skipped worker should be recovered")
+}
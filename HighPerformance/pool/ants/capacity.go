@@ -0,0 +1,65 @@
+package ants
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// WaitForCapacity 阻塞到pool能马上接收n个任务为止，也就是空闲的worker加上还能创建的worker不少于n个，
+// 这时调用SubmitNAtomic提交n个任务通常就会成功（其他提交者仍然可能先拿走worker），不需要自己循环检查Free()。
+// 空闲的worker也计入在内，因为Free()不包括它们，只看Free()的话要等空闲的worker过期退出。
+// 成功返回nil，timeout之内没有等到返回context.DeadlineExceeded，n超过容量返回ErrInsufficientCapacity，pool关闭返回ErrPoolClosed
+func (p *Pool) WaitForCapacity(n int, timeout time.Duration) error {
+	if capacity := p.Cap(); capacity != -1 && n > capacity {
+		return ErrInsufficientCapacity
+	}
+	var timedOut bool
+	timer := time.AfterFunc(timeout, func() {
+		p.lock.Lock()
+		timedOut = true
+		p.capacityCond.Broadcast()
+		p.lock.Unlock()
+	})
+	defer timer.Stop()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	atomic.AddInt32(&p.capacityWaiters, 1)
+	defer atomic.AddInt32(&p.capacityWaiters, -1)
+	for {
+		if p.IsClosed() {
+			return ErrPoolClosed
+		}
+		if p.available() >= n {
+			return nil
+		}
+		if timedOut {
+			return context.DeadlineExceeded
+		}
+		p.capacityCond.Wait()
+	}
+}
+
+// available 返回空闲的worker加上还能创建的worker的数量，需要持有pool.lock
+func (p *Pool) available() int {
+	capacity := p.Cap()
+	if capacity == -1 {
+		return maxInt
+	}
+	n := p.workers.Len() + capacity - p.Running()
+	if atomic.LoadPointer(&p.hotWorker) != nil {
+		n++
+	}
+	return n
+}
+
+// notifyCapacity 在可用的worker变多（worker归还或者退出）的时候调用，有WaitForCapacity的等待者的时候唤醒它们，
+// 不能持有pool.lock
+func (p *Pool) notifyCapacity() {
+	if atomic.LoadInt32(&p.capacityWaiters) > 0 {
+		p.lock.Lock()
+		p.capacityCond.Broadcast()
+		p.lock.Unlock()
+	}
+}
@@ -0,0 +1,80 @@
+package ants
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// CapSnapshot 是一次容量变化的记录
+type CapSnapshot struct {
+	// 变化发生的时间
+	Timestamp time.Time `json:"timestamp"`
+
+	OldCap int `json:"oldCap"`
+	NewCap int `json:"newCap"`
+
+	// 调用Tune、AdjustCapacity等方法的位置，格式为file:line
+	Caller string `json:"caller"`
+}
+
+// capHistory 用环形缓冲保存最近的容量变化
+type capHistory struct {
+	lock    sync.Mutex
+	entries []CapSnapshot
+	// next 下一次写入的位置，full 缓冲是否已经写满过
+	next int
+	full bool
+}
+
+func newCapHistory(maxEntries int) *capHistory {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &capHistory{entries: make([]CapSnapshot, maxEntries)}
+}
+
+func (h *capHistory) record(s CapSnapshot) {
+	h.lock.Lock()
+	h.entries[h.next] = s
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.lock.Unlock()
+}
+
+// snapshot 按时间顺序返回所有的记录的拷贝
+func (h *capHistory) snapshot() []CapSnapshot {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.full {
+		return append([]CapSnapshot(nil), h.entries[:h.next]...)
+	}
+	s := make([]CapSnapshot, 0, len(h.entries))
+	s = append(s, h.entries[h.next:]...)
+	return append(s, h.entries[:h.next]...)
+}
+
+// CapHistory 按时间顺序返回最近的容量变化，最多CapHistory条，没有开启CapHistory的时候返回nil。
+// 返回的是拷贝，用来在事后分析容量是否在反复地调整
+func (p *Pool) CapHistory() []CapSnapshot {
+	if p.capHistory == nil {
+		return nil
+	}
+	return p.capHistory.snapshot()
+}
+
+// recordCapChange 在容量从oldCap变成newCap之后调用，skip是从调用recordCapChange的方法到要记录的调用者需要跳过的栈帧数
+func (p *Pool) recordCapChange(oldCap, newCap, skip int) {
+	h := p.capHistory
+	if h == nil || oldCap == newCap {
+		return
+	}
+	var caller string
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	h.record(CapSnapshot{Timestamp: time.Now(), OldCap: oldCap, NewCap: newCap, Caller: caller})
+}
@@ -0,0 +1,52 @@
+package ants
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapHistory(t *testing.T) {
+	_, err := NewPool(10, WithCapHistory(-1))
+	assert.EqualError(t, err, ErrInvalidPoolConfig.Error())
+
+	p, err := NewPool(10, WithCapHistory(3))
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.Empty(t, p.CapHistory())
+
+	start := time.Now()
+	p.Tune(20)
+	p.Tune(20)
+	history := p.CapHistory()
+	assert.Len(t, history, 1, "unchanged capacity should not be recorded")
+	assert.Equal(t, 10, history[0].OldCap)
+	assert.Equal(t, 20, history[0].NewCap)
+	assert.False(t, history[0].Timestamp.Before(start))
+	assert.True(t, strings.Contains(history[0].Caller, "caphistory_test.go:"), "caller should be the test, got %s", history[0].Caller)
+
+	// 超过maxEntries之后只保留最近的记录
+	assert.NoError(t, p.AdjustCapacity(5))
+	assert.Equal(t, 0, p.Shrink(15))
+	assert.NoError(t, p.MigrateTo(12))
+	history = p.CapHistory()
+	assert.Len(t, history, 3)
+	var caps [][2]int
+	for _, s := range history {
+		caps = append(caps, [2]int{s.OldCap, s.NewCap})
+		assert.True(t, strings.Contains(s.Caller, "caphistory_test.go:") || strings.Contains(s.Caller, "pool.go:"))
+	}
+	assert.Equal(t, [][2]int{{20, 25}, {25, 15}, {15, 12}}, caps)
+
+	// 返回的是拷贝
+	history[0].NewCap = 0
+	assert.Equal(t, 25, p.CapHistory()[0].NewCap)
+
+	p1, err := NewPool(10)
+	assert.NoError(t, err)
+	defer p1.Release()
+	p1.Tune(20)
+	assert.Nil(t, p1.CapHistory())
+}
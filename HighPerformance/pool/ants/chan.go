@@ -0,0 +1,51 @@
+package ants
+
+// Chan 返回一个可以直接发送任务的channel，pool用一个goroutine从中读出任务并调用Submit，
+// pool已满的时候读取会阻塞，发送方因此得到背压；缓冲大小通过ChanBufferSize设置。
+// 多次调用返回同一个channel，不再发送的时候调用CloseChan关闭它，不要直接close：
+// 直接关闭和Chan之间没有同步，Chan可能返回已经关闭的channel。
+// 提交失败的任务会被丢弃并记录日志；pool被Release之后不再读取channel，剩下的任务被丢弃，调用者应该停止发送
+func (p *Pool) Chan() chan<- func() {
+	p.chanLock.Lock()
+	defer p.chanLock.Unlock()
+	if p.taskChan == nil {
+		p.taskChan = make(chan func(), p.options.ChanBufferSize)
+		go p.feedFromChan(p.taskChan, p.ShutdownContext().Done())
+	}
+	return p.taskChan
+}
+
+// CloseChan 关闭Chan返回的channel，已经发送的任务会被继续提交完，pool不会因此被关闭，
+// 之后再调用Chan会返回一个新的channel。没有打开的channel的时候什么都不做
+func (p *Pool) CloseChan() {
+	p.chanLock.Lock()
+	defer p.chanLock.Unlock()
+	if p.taskChan != nil {
+		close(p.taskChan)
+		p.taskChan = nil
+	}
+}
+
+// feedFromChan 把ch中的任务提交到pool中，直到ch被关闭并且读完，或者pool被关闭
+func (p *Pool) feedFromChan(ch chan func(), shutdown <-chan struct{}) {
+	defer func() {
+		p.chanLock.Lock()
+		if p.taskChan == ch {
+			p.taskChan = nil
+		}
+		p.chanLock.Unlock()
+	}()
+	for {
+		select {
+		case <-shutdown:
+			return
+		case task, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := p.Submit(task); err != nil {
+				p.options.Logger.Printf("task from Chan dropped: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChan(t *testing.T) {
+	p, err := NewPool(10, WithChanBufferSize(8))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	ch := p.Chan()
+	assert.Equal(t, ch, p.Chan(), "Chan should return the same channel")
+	assert.Equal(t, 8, cap(ch))
+
+	var (
+		n  int32
+		wg sync.WaitGroup
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		ch <- func() {
+			atomic.AddInt32(&n, 1)
+			wg.Done()
+		}
+	}
+	p.CloseChan()
+	wg.Wait()
+	assert.EqualValues(t, 5, atomic.LoadInt32(&n), "tasks sent before closing should be drained")
+	assert.False(t, p.IsClosed(), "closing the channel should not release the pool")
+
+	// 关闭之后马上返回新的channel
+	ch2 := p.Chan()
+	assert.NotEqual(t, ch, ch2)
+	p.CloseChan()
+	p.CloseChan()
+}
+
+func TestChanStopsOnRelease(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	ch := p.Chan()
+	p.Release()
+
+	// pool关闭之后读取channel的goroutine退出，不会一直等着调用者关闭channel
+	eventually(t, func() bool {
+		p.chanLock.Lock()
+		defer p.chanLock.Unlock()
+		return p.taskChan == nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case ch <- demoFunc:
+		t.Fatal("nobody should read the channel after Release")
+	default:
+	}
+}
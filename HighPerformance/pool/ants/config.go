@@ -0,0 +1,120 @@
+package ants
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolConfig 是pool配置的可序列化形式，方便从配置文件中创建pool
+type PoolConfig struct {
+	// pool的容量，小于等于0代表不限制容量
+	Capacity int `json:"capacity"`
+
+	// 清理过期worker的时间间隔，单位为毫秒，为0时使用DefaultCleanIntervalTime
+	ExpiryMs int64 `json:"expiryMs"`
+
+	// 是否在初始化pool的时候，预先申请内存
+	PreAlloc bool `json:"preAlloc"`
+
+	// 当为true的时候，Pool.Submit永远不会被阻塞
+	Nonblocking bool `json:"nonblocking"`
+
+	// 允许阻塞在pool.Submit上的最大goroutine的数量，0代表没有限制
+	MaxBlockingTasks int `json:"maxBlockingTasks"`
+
+	// 是否关闭定期清理过期worker
+	DisablePurge bool `json:"disablePurge"`
+}
+
+// validate 校验配置中的值是否合法
+func (cfg PoolConfig) validate() error {
+	if cfg.ExpiryMs < 0 {
+		return ErrInvalidPoolExpiry
+	}
+	if cfg.PreAlloc && cfg.Capacity <= 0 {
+		return ErrInvalidPreAllocSize
+	}
+	if cfg.MaxBlockingTasks < 0 {
+		return ErrInvalidPoolConfig
+	}
+	return nil
+}
+
+// options 将配置转换为对应的Option
+func (cfg PoolConfig) options() []Option {
+	return []Option{
+		WithExpiryDuration(time.Duration(cfg.ExpiryMs) * time.Millisecond),
+		WithPreAlloc(cfg.PreAlloc),
+		WithNonblocking(cfg.Nonblocking),
+		WithMaxBlockingTasks(cfg.MaxBlockingTasks),
+		WithDisablePurge(cfg.DisablePurge),
+	}
+}
+
+// NewPoolFromConfig 根据配置创建一个pool实例，options会在配置之后生效，可以用来设置PanicHandler、Logger等无法序列化的配置
+func NewPoolFromConfig(cfg PoolConfig, options ...Option) (*Pool, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return NewPool(cfg.Capacity, append(cfg.options(), options...)...)
+}
+
+// ConfigOf 导出pool当前的配置，可以再通过NewPoolFromConfig创建一个相同配置的pool
+func ConfigOf(p *Pool) PoolConfig {
+	return PoolConfig{
+		Capacity:         p.Cap(),
+		ExpiryMs:         expiryMs(p.options.ExpiryDuration),
+		PreAlloc:         p.options.PreAlloc,
+		Nonblocking:      p.isNonblocking(),
+		MaxBlockingTasks: p.options.MaxBlockingTasks,
+		DisablePurge:     p.options.DisablePurge,
+	}
+}
+
+// expiryMs 把过期时间换算成毫秒，不足一毫秒的部分向上取整，
+// 否则小于一毫秒的过期时间会变成0，NewPoolFromConfig的时候被当成默认的清理间隔
+func expiryMs(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// PoolState 是pool某一时刻状态的可序列化快照，包括配置和各种计数器，
+// 正在运行的goroutine是无法保存的，Restore不会恢复它们，计数器仅用于观察
+type PoolState struct {
+	// pool的配置
+	Config PoolConfig `json:"config"`
+
+	// pool是否已经关闭
+	Closed bool `json:"closed"`
+
+	// 快照时正在运行的goroutine的数量
+	Running int `json:"running"`
+
+	// 快照时空闲的worker的数量
+	Idle int `json:"idle"`
+
+	// 快照时阻塞在Submit上的goroutine的数量
+	Blocking int `json:"blocking"`
+}
+
+// Checkpoint 保存pool当前的状态
+func (p *Pool) Checkpoint() PoolState {
+	return PoolState{
+		Config:   ConfigOf(p),
+		Closed:   p.IsClosed(),
+		Running:  p.Running(),
+		Idle:     p.LenIdle(),
+		Blocking: int(atomic.LoadInt32(&p.blockingNum)),
+	}
+}
+
+// Restore 根据快照创建一个新的pool，快照中的goroutine不会被恢复，所以新pool的计数器都是从0开始的
+func Restore(state PoolState, options ...Option) (*Pool, error) {
+	p, err := NewPoolFromConfig(state.Config, options...)
+	if err != nil {
+		return nil, err
+	}
+	if state.Closed {
+		p.Release()
+	}
+	return p, nil
+}
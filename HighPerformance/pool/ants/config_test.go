@@ -0,0 +1,111 @@
+package ants
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolConfigMarshal(t *testing.T) {
+	cfg := PoolConfig{
+		Capacity:         10,
+		ExpiryMs:         500,
+		PreAlloc:         true,
+		Nonblocking:      true,
+		MaxBlockingTasks: 5,
+		DisablePurge:     true,
+	}
+	data, err := json.Marshal(cfg)
+	assert.NoErrorf(t, err, "marshal config failed: %v", err)
+
+	var decoded PoolConfig
+	assert.NoError(t, json.Unmarshal(data, &decoded), "unmarshal config failed")
+	assert.Equal(t, cfg, decoded, "config should survive a json round-trip")
+
+	decoded = PoolConfig{}
+	raw := `{"capacity":3,"expiryMs":2000,"nonblocking":true}`
+	assert.NoError(t, json.Unmarshal([]byte(raw), &decoded), "unmarshal config failed")
+	assert.Equal(t, PoolConfig{Capacity: 3, ExpiryMs: 2000, Nonblocking: true}, decoded)
+}
+
+func TestNewPoolFromConfig(t *testing.T) {
+	cfg := PoolConfig{Capacity: 1, ExpiryMs: 200, Nonblocking: true}
+	p, err := NewPoolFromConfig(cfg)
+	assert.NoErrorf(t, err, "create pool from config failed: %v", err)
+	defer p.Release()
+
+	p1, err := NewPool(1, WithExpiryDuration(200*time.Millisecond), WithNonblocking(true))
+	assert.NoErrorf(t, err, "create pool failed: %v", err)
+	defer p1.Release()
+
+	assert.Equal(t, ConfigOf(p1), ConfigOf(p), "config should match the equivalent functional options")
+	assert.Equal(t, cfg, ConfigOf(p), "ConfigOf should dump the settings used to create the pool")
+
+	// 两个pool在满载的时候应当有相同的行为
+	ch := make(chan struct{})
+	defer close(ch)
+	for _, pool := range []*Pool{p, p1} {
+		assert.NoError(t, pool.Submit(func() { <-ch }), "submit when pool is not full shouldn't return error")
+		assert.EqualError(t, pool.Submit(demoFunc), ErrPoolOverload.Error(),
+			"nonblocking submit when pool is full should get an ErrPoolOverload")
+	}
+
+	_, err = NewPoolFromConfig(PoolConfig{Capacity: 1, ExpiryMs: -1})
+	assert.Equal(t, ErrInvalidPoolExpiry, err)
+	_, err = NewPoolFromConfig(PoolConfig{Capacity: -1, PreAlloc: true})
+	assert.Equal(t, ErrInvalidPreAllocSize, err)
+	_, err = NewPoolFromConfig(PoolConfig{Capacity: 1, MaxBlockingTasks: -1})
+	assert.Equal(t, ErrInvalidPoolConfig, err)
+
+	// 不足一毫秒的过期时间向上取整，不会变成0而被当成默认值
+	p2, err := NewPool(1, WithExpiryDuration(500*time.Microsecond))
+	assert.NoError(t, err)
+	defer p2.Release()
+	assert.EqualValues(t, 1, ConfigOf(p2).ExpiryMs)
+	p3, err := NewPoolFromConfig(ConfigOf(p2))
+	assert.NoError(t, err)
+	defer p3.Release()
+	assert.Equal(t, time.Millisecond, p3.options.ExpiryDuration)
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	p, err := NewPool(2, WithExpiryDuration(time.Second), WithMaxBlockingTasks(3))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	ch := make(chan struct{})
+	defer close(ch)
+	_ = p.Submit(func() { <-ch })
+
+	state := p.Checkpoint()
+	data, err := json.Marshal(state)
+	assert.NoErrorf(t, err, "marshal state failed: %v", err)
+	golden := `{"config":{"capacity":2,"expiryMs":1000,"preAlloc":false,"nonblocking":false,` +
+		`"maxBlockingTasks":3,"disablePurge":false},"closed":false,"running":1,"idle":0,"blocking":0}`
+	assert.JSONEq(t, golden, string(data))
+
+	var decoded PoolState
+	assert.NoError(t, json.Unmarshal(data, &decoded), "unmarshal state failed")
+	p1, err := Restore(decoded)
+	assert.NoErrorf(t, err, "restore pool failed: %v", err)
+	defer p1.Release()
+	restored := p1.Checkpoint()
+	assert.Equal(t, state.Config, restored.Config, "config should be restored")
+	assert.EqualValues(t, 0, restored.Running, "goroutines shouldn't be restored")
+
+	p.Release()
+	p2, err := Restore(p.Checkpoint())
+	assert.NoErrorf(t, err, "restore pool failed: %v", err)
+	assert.True(t, p2.IsClosed(), "closed state should be restored")
+
+	// hotWorker中的worker也计入空闲
+	hp, err := NewPool(2, WithHotWorker(true), WithDisablePurge(true))
+	assert.NoError(t, err)
+	defer hp.Release()
+	hp.incRunning()
+	defer hp.decRunning()
+	hp.revertWorker(&goWorker{pool: hp, task: make(chan func(), 1)})
+	assert.Equal(t, 1, hp.Checkpoint().Idle)
+}
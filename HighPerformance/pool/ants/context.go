@@ -0,0 +1,192 @@
+package ants
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/panjf2000/ants/v2/internal"
+)
+
+// poolContextKey 是基础context中保存pool的key
+type poolContextKey struct{}
+
+// contextHolder 包装context，使它可以存放在atomic.Value中
+type contextHolder struct {
+	ctx context.Context
+}
+
+// taskWorkers 保存正在运行任务的worker，key为worker的goroutine的id，只有设置了基础context或者WorkerLocalInit的pool的worker才会注册
+var taskWorkers sync.Map
+
+// SetBaseContext 原子地替换pool的基础context，之后开始执行的任务都可以通过TaskContext拿到它，
+// 已经在执行的任务不受影响，PinnedPool创建的子pool也会一起替换。ctx为nil的时候当成context.Background()，任务仍然可以通过FromContext拿到pool
+func (p *Pool) SetBaseContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p.baseCtx.Store(contextHolder{ctx: context.WithValue(ctx, poolContextKey{}, p)})
+
+	p.pinnedLock.Lock()
+	defer p.pinnedLock.Unlock()
+	for _, sub := range p.pinned {
+		sub.SetBaseContext(ctx)
+	}
+}
+
+// baseContext 返回pool的基础context，没有设置的时候返回nil
+func (p *Pool) baseContext() context.Context {
+	if h, ok := p.baseCtx.Load().(contextHolder); ok {
+		return h.ctx
+	}
+	return nil
+}
+
+// TaskContext 在任务中调用，返回任务开始执行时pool的基础context，
+// 可以通过FromContext拿到运行任务的pool，不在任务中或者pool没有设置基础context的时候返回context.Background()
+func TaskContext() context.Context {
+	if w, ok := taskWorkers.Load(internal.GoID()); ok {
+		if ctx := w.(*goWorker).ctx; ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// FromContext 返回保存在ctx中的pool，没有的时候返回nil
+func FromContext(ctx context.Context) *Pool {
+	p, _ := ctx.Value(poolContextKey{}).(*Pool)
+	return p
+}
+
+// injectContext 在执行任务之前调用，把基础context注入到worker中，没有设置基础context的时候什么也不做
+func (w *goWorker) injectContext() {
+	ctx := w.pool.baseContext()
+	if ctx == nil && w.pool.options.WorkerLocalInit == nil {
+		return
+	}
+	if w.goid == 0 {
+		w.goid = internal.GoID()
+		taskWorkers.Store(w.goid, w)
+	}
+	w.ctx = ctx
+}
+
+// clearContext 在worker退出的时候调用
+func (w *goWorker) clearContext() {
+	if w.goid != 0 {
+		taskWorkers.Delete(w.goid)
+		w.goid = 0
+	}
+	w.ctx = nil
+	w.local = nil
+}
+
+// shutdownHolder 保存关闭信号的context和取消它的函数
+type shutdownHolder struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ShutdownContext 返回pool的关闭信号，Release(或者ReleaseContext、ReleaseTimeout)开始的时候它会被取消，
+// 长时间运行的任务可以通过它得知pool正在关闭，尽快退出。Reboot之后需要重新获取
+func (p *Pool) ShutdownContext() context.Context {
+	return p.shutdown.Load().(shutdownHolder).ctx
+}
+
+// SubmitCtx 提交一个接收关闭信号的任务，任务拿到的ctx就是提交时的ShutdownContext
+func (p *Pool) SubmitCtx(task func(ctx context.Context)) error {
+	ctx := p.ShutdownContext()
+	return p.Submit(func() {
+		task(ctx)
+	})
+}
+
+// SubmitCtxValue 代表一个请求提交任务，任务拿到的ctx合并了调用者的ctx和pool的基础context：
+// 取消和截止时间来自调用者的ctx，值先在调用者的ctx中找，找不到再到基础context中找，比如请求的trace-id和基础context中的配置都能拿到，
+// 也可以通过FromContext拿到pool。基础context在提交的时候取出，ctx在提交时已经被取消的话任务仍然会执行，由任务自己检查
+func (p *Pool) SubmitCtxValue(ctx context.Context, task func(ctx context.Context)) error {
+	merged := context.WithValue(mergedContext{Context: ctx, base: p.baseContext()}, poolContextKey{}, p)
+	return p.Submit(func() {
+		task(merged)
+	})
+}
+
+// SubmitWithContext 提交一个受ctx控制的任务：阻塞等待worker的时候ctx被取消会放弃提交并返回ErrContextCancelled，
+// 提交之后还在worker的任务channel或者Chan()的缓冲中等待的时候ctx被取消，worker拿到它的时候会跳过而不执行，计入Stats().ExpiredTasks
+func (p *Pool) SubmitWithContext(ctx context.Context, task func()) error {
+	if ctx.Err() != nil {
+		return ErrContextCancelled
+	}
+	if dropped, err := p.admit(); dropped || err != nil {
+		return err
+	}
+	run := func() {
+		if ctx.Err() != nil {
+			atomic.AddUint64(&p.expiredTasks, 1)
+			return
+		}
+		task()
+	}
+	if p.options.Synchronous {
+		p.runInline(run)
+		return nil
+	}
+	exec, untrack := p.trackPending(task, run)
+	stop := p.wakeOnDone(ctx)
+	w, cancelled := p.retrieveWorkerOrCancel(ctx)
+	stop()
+	if w == nil {
+		untrack()
+		if cancelled {
+			return ErrTaskCancelled
+		}
+		if ctx.Err() != nil {
+			return ErrContextCancelled
+		}
+		return p.overflow(run)
+	}
+	w.dispatch(exec)
+	return nil
+}
+
+// wakeOnDone ctx被取消的时候唤醒阻塞在retrieveWorker()中的提交者，让它们检查自己的ctx，返回的函数用来停止等待
+func (p *Pool) wakeOnDone(ctx context.Context) (stop func()) {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			p.lock.Lock()
+			p.cond.Broadcast()
+			p.lock.Unlock()
+		case <-stopped:
+		}
+	}()
+	return func() { close(stopped) }
+}
+
+// mergedContext 在Context中找不到的值再到base中找
+type mergedContext struct {
+	context.Context
+	base context.Context
+}
+
+func (c mergedContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	if c.base != nil {
+		return c.base.Value(key)
+	}
+	return nil
+}
+
+// resetShutdown 创建新的关闭信号，在创建pool和Reboot的时候调用
+func (p *Pool) resetShutdown() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.shutdown.Store(shutdownHolder{ctx: ctx, cancel: cancel})
+}
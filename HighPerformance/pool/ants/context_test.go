@@ -0,0 +1,178 @@
+package ants
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type traceKey struct{}
+
+func TestBaseContext(t *testing.T) {
+	base := context.WithValue(context.Background(), traceKey{}, "span-1")
+	p, err := NewPool(10, WithBaseContext(base))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var (
+		wg   sync.WaitGroup
+		ctx  context.Context
+		pool *Pool
+	)
+	run := func() {
+		wg.Add(1)
+		_ = p.Submit(func() {
+			defer wg.Done()
+			ctx = TaskContext()
+			pool = FromContext(ctx)
+		})
+		wg.Wait()
+	}
+
+	run()
+	assert.Equal(t, p, pool, "pool should be retrieved from the task context")
+	assert.Equal(t, "span-1", ctx.Value(traceKey{}))
+
+	p.SetBaseContext(context.WithValue(context.Background(), traceKey{}, "span-2"))
+	run()
+	assert.Equal(t, p, pool)
+	assert.Equal(t, "span-2", ctx.Value(traceKey{}), "replaced base context should be propagated")
+
+	// nil被当成context.Background()
+	assert.NotPanics(t, func() { p.SetBaseContext(nil) })
+	run()
+	assert.Equal(t, p, pool)
+	assert.Nil(t, ctx.Value(traceKey{}))
+
+	assert.Nil(t, FromContext(TaskContext()), "no pool should be found outside tasks")
+
+	// 没有设置基础context的pool
+	p1, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	wg.Add(1)
+	_ = p1.Submit(func() {
+		defer wg.Done()
+		pool = FromContext(TaskContext())
+	})
+	wg.Wait()
+	assert.Nil(t, pool)
+}
+
+func TestShutdownContext(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+
+	started := make(chan struct{})
+	observed := make(chan error, 1)
+	err = p.SubmitCtx(func(ctx context.Context) {
+		close(started)
+		for {
+			select {
+			case <-ctx.Done():
+				observed <- ctx.Err()
+				return
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+	assert.NoError(t, err)
+	<-started
+	assert.NoError(t, p.ShutdownContext().Err(), "shutdown context should not be cancelled before Release")
+
+	// worker执行完任务之后还要过一段时间才会退出，这里给足等待时间
+	assert.NoError(t, p.ReleaseTimeout(15*time.Second), "pool should drain after the task winds down")
+	select {
+	case err = <-observed:
+		assert.Equal(t, context.Canceled, err, "task should observe the shutdown")
+	default:
+		t.Fatal("task did not observe the shutdown")
+	}
+	assert.EqualValues(t, 0, p.Running())
+
+	p.Reboot()
+	assert.NoError(t, p.ShutdownContext().Err(), "Reboot should install a fresh shutdown context")
+	p.Release()
+	assert.Error(t, p.ShutdownContext().Err())
+}
+
+type tenantKey struct{}
+
+func TestSubmitCtxValue(t *testing.T) {
+	base := context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+	p, err := NewPool(10, WithBaseContext(base))
+	assert.NoError(t, err)
+	defer p.Release()
+
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), traceKey{}, "trace-42"))
+	done := make(chan context.Context, 1)
+	assert.NoError(t, p.SubmitCtxValue(reqCtx, func(ctx context.Context) {
+		done <- ctx
+	}))
+	ctx := <-done
+	assert.Equal(t, "trace-42", ctx.Value(traceKey{}), "value of the request context should be carried")
+	assert.Equal(t, "tenant-a", ctx.Value(tenantKey{}), "value of the base context should be merged")
+	assert.Equal(t, p, FromContext(ctx))
+	assert.NoError(t, ctx.Err())
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err(), "cancellation should come from the request context")
+
+	// 调用者的值优先于基础context中的值
+	p.SetBaseContext(context.WithValue(context.Background(), traceKey{}, "base-trace"))
+	assert.NoError(t, p.SubmitCtxValue(context.WithValue(context.Background(), traceKey{}, "trace-43"), func(ctx context.Context) {
+		done <- ctx
+	}))
+	assert.Equal(t, "trace-43", (<-done).Value(traceKey{}))
+
+	// 没有设置基础context的pool
+	p1, err := NewPool(10)
+	assert.NoError(t, err)
+	defer p1.Release()
+	assert.NoError(t, p1.SubmitCtxValue(reqCtx, func(ctx context.Context) {
+		done <- ctx
+	}))
+	ctx = <-done
+	assert.Equal(t, "trace-42", ctx.Value(traceKey{}))
+	assert.Nil(t, ctx.Value(tenantKey{}))
+	assert.Equal(t, p1, FromContext(ctx))
+}
+
+func TestSubmitWithContext(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, ErrContextCancelled, p.SubmitWithContext(cancelled, demoFunc))
+
+	// 任务已经进入worker的缓冲，在worker拿到它之前ctx超时，应该被跳过
+	p.incRunning()
+	defer p.decRunning()
+	w := &goWorker{pool: p, task: make(chan func(), 4)}
+	var ran []int
+	for i, timeout := range []time.Duration{10 * time.Millisecond, time.Minute} {
+		i := i
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		p.revertWorker(w)
+		assert.NoError(t, p.SubmitWithContext(ctx, func() { ran = append(ran, i) }))
+	}
+	time.Sleep(20 * time.Millisecond)
+	(<-w.task)()
+	(<-w.task)()
+	assert.Equal(t, []int{1}, ran, "only the task whose context is still alive should run")
+	assert.EqualValues(t, 1, p.Stats().ExpiredTasks)
+
+	// 阻塞等待worker的时候ctx超时
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, ErrContextCancelled, p.SubmitWithContext(ctx, demoFunc))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	assert.EqualValues(t, 0, p.Stats().Blocking)
+}
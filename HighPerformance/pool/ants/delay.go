@@ -0,0 +1,148 @@
+package ants
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// DelayedTask 是一个延迟执行的任务
+type DelayedTask struct {
+	Task  func()
+	Delay time.Duration
+}
+
+// SubmitAfter 在delay之后把task提交到pool中，到时提交失败的时候任务会被丢弃并记录日志，pool关闭的时候还没有到时间的任务会被丢弃
+func (p *Pool) SubmitAfter(task func(), delay time.Duration) error {
+	return p.SubmitAfterMany([]DelayedTask{{Task: task, Delay: delay}})
+}
+
+// SubmitAfterMany 批量提交延迟任务，每个任务在各自的Delay之后提交到pool中，pool已经关闭的时候返回ErrPoolClosed，
+// 加上tasks之后超过MaxDelayedTasks的时候整批拒绝，返回ErrDelayQueueFull。
+// 所有的延迟任务共用一个按到期时间排序的队列和一个定时器，Release的时候还没有到时间的任务会被丢弃，不会在关闭之后再提交
+func (p *Pool) SubmitAfterMany(tasks []DelayedTask) error {
+	now := nanotime()
+	p.delayLock.Lock()
+	defer p.delayLock.Unlock()
+	// 在锁内检查，Release先修改状态再清空队列，不会有任务在清空之后加进来
+	if p.IsClosed() {
+		return ErrPoolClosed
+	}
+	if max := p.options.MaxDelayedTasks; max > 0 && len(p.delayed)+len(tasks) > max {
+		return ErrDelayQueueFull
+	}
+	for _, t := range tasks {
+		p.delaySeq++
+		heap.Push(&p.delayed, &delayedItem{task: t.Task, at: now + int64(t.Delay), seq: p.delaySeq})
+	}
+	p.armDelayTimer()
+	return nil
+}
+
+// delayedItem 是队列中的一个延迟任务，at是到期的nanotime
+type delayedItem struct {
+	task func()
+	at   int64
+	seq  uint64
+}
+
+// delayHeap 按到期时间排序的小顶堆，实现了heap.Interface
+type delayHeap []*delayedItem
+
+func (h delayHeap) Len() int { return len(h) }
+
+func (h delayHeap) Less(i, j int) bool {
+	if h[i].at != h[j].at {
+		return h[i].at < h[j].at
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h delayHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *delayHeap) Push(x interface{}) { *h = append(*h, x.(*delayedItem)) }
+
+func (h *delayHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// armDelayTimer 让定时器在最早的任务到期的时候触发，需要持有delayLock
+func (p *Pool) armDelayTimer() {
+	if len(p.delayed) == 0 {
+		return
+	}
+	d := time.Duration(p.delayed[0].at - nanotime())
+	if p.delayTimer == nil {
+		p.delayTimer = time.AfterFunc(d, p.fireDelayed)
+		return
+	}
+	p.delayTimer.Stop()
+	p.delayTimer.Reset(d)
+}
+
+// fireDelayed 取出所有已经到期的任务并提交，然后为下一个任务重新设置定时器。
+// 提交在新的goroutine中按到期的顺序进行，pool已满的时候不会耽误后面的任务到期
+func (p *Pool) fireDelayed() {
+	now := nanotime()
+	p.delayLock.Lock()
+	var due []func()
+	for len(p.delayed) > 0 && p.delayed[0].at <= now {
+		due = append(due, heap.Pop(&p.delayed).(*delayedItem).task)
+	}
+	p.armDelayTimer()
+	p.delayLock.Unlock()
+	if len(due) == 0 {
+		return
+	}
+	go func() {
+		for _, task := range due {
+			if err := p.Submit(task); err != nil {
+				p.options.Logger.Printf("delayed task dropped: %v\n", err)
+			}
+		}
+	}()
+}
+
+// dropDelayed 丢弃所有还没有到时间的延迟任务并停止定时器，在Release中调用
+func (p *Pool) dropDelayed() {
+	p.delayLock.Lock()
+	for i := range p.delayed {
+		p.delayed[i] = nil
+	}
+	p.delayed = p.delayed[:0]
+	if p.delayTimer != nil {
+		p.delayTimer.Stop()
+	}
+	p.delayLock.Unlock()
+}
+
+// Stagger 逐个提交tasks，相邻两次提交之间间隔delay，避免突发的大量任务压垮下游，
+// 和SubmitAfterMany不同，间隔是在提交之间而不是每个任务各自延迟。返回第一个提交失败的错误，之后的任务不再提交；
+// ctx在等待间隔或者阻塞等待worker的时候被取消会停止提交并返回ErrContextCancelled
+func (p *Pool) Stagger(ctx context.Context, tasks []func(), delay time.Duration) error {
+	var timer *time.Timer
+	for i, task := range tasks {
+		if i > 0 && delay > 0 {
+			if timer == nil {
+				timer = time.NewTimer(delay)
+				defer timer.Stop()
+			} else {
+				timer.Reset(delay)
+			}
+			select {
+			case <-ctx.Done():
+				return ErrContextCancelled
+			case <-timer.C:
+			}
+		}
+		if err := p.SubmitBatchCtx(ctx, []func(){task})[0]; err != nil {
+			return err
+		}
+	}
+	return nil
+}
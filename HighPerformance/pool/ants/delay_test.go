@@ -0,0 +1,119 @@
+package ants
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitAfterMany(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	task := func(i int) func() {
+		return func() {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}
+	}
+	wg.Add(3)
+	start := time.Now()
+	err = p.SubmitAfterMany([]DelayedTask{
+		{Task: task(2), Delay: 60 * time.Millisecond},
+		{Task: task(0), Delay: 20 * time.Millisecond},
+		{Task: task(1), Delay: 40 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+	wg.Wait()
+	assert.True(t, time.Since(start) >= 60*time.Millisecond, "tasks should not run before their delay")
+	assert.Equal(t, []int{0, 1, 2}, order, "tasks should run in the order of their delays")
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.SubmitAfterMany([]DelayedTask{{Task: func() {}}}))
+	assert.Equal(t, ErrPoolClosed, p.SubmitAfter(func() {}, time.Millisecond))
+}
+
+func TestSubmitAfterDroppedOnRelease(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var ran int32
+	assert.NoError(t, p.SubmitAfterMany([]DelayedTask{
+		{Task: func() { atomic.AddInt32(&ran, 1) }, Delay: 30 * time.Millisecond},
+		{Task: func() { atomic.AddInt32(&ran, 1) }, Delay: 20 * time.Millisecond},
+	}))
+	p.Release()
+	time.Sleep(60 * time.Millisecond)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&ran), "delayed tasks should be dropped when the pool is released")
+
+	// 重启之后新的延迟任务照常执行
+	assert.True(t, p.Reboot())
+	done := make(chan struct{})
+	assert.NoError(t, p.SubmitAfter(func() { close(done) }, time.Millisecond))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("delayed task should run after reboot")
+	}
+}
+
+func TestMaxDelayedTasks(t *testing.T) {
+	_, err := NewPool(10, WithMaxDelayedTasks(-1))
+	assert.Equal(t, ErrInvalidPoolConfig, err)
+
+	p, err := NewPool(10, WithMaxDelayedTasks(2))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var ran int32
+	task := func() { atomic.AddInt32(&ran, 1) }
+	assert.NoError(t, p.SubmitAfter(task, 20*time.Millisecond))
+	// 超过上限的时候整批拒绝
+	assert.Equal(t, ErrDelayQueueFull, p.SubmitAfterMany([]DelayedTask{{Task: task, Delay: time.Millisecond}, {Task: task, Delay: time.Millisecond}}))
+	assert.NoError(t, p.SubmitAfter(task, 20*time.Millisecond))
+	assert.Equal(t, ErrDelayQueueFull, p.SubmitAfter(task, time.Millisecond))
+
+	// 到时间的任务离开队列之后又可以提交
+	eventually(t, func() bool { return atomic.LoadInt32(&ran) == 2 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, p.SubmitAfter(task, time.Millisecond))
+}
+
+func TestStagger(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	task := func() {}
+	start := time.Now()
+	assert.NoError(t, p.Stagger(context.Background(), []func(){task, task, task}, 20*time.Millisecond))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond, "submissions should be spaced by delay")
+
+	// 在间隔中取消
+	ctx, cancel := context.WithCancel(context.Background())
+	var submitted int32
+	time.AfterFunc(75*time.Millisecond, cancel)
+	err = p.Stagger(ctx, []func(){
+		func() { atomic.AddInt32(&submitted, 1) },
+		func() { atomic.AddInt32(&submitted, 1) },
+		func() { atomic.AddInt32(&submitted, 1) },
+	}, 50*time.Millisecond)
+	assert.Equal(t, ErrContextCancelled, err)
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&submitted), "tasks after cancellation should not be submitted")
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, p.Stagger(context.Background(), []func(){task}, 0))
+}
@@ -0,0 +1,89 @@
+package ants
+
+import (
+	"sort"
+	"time"
+
+	"github.com/panjf2000/ants/v2/internal"
+)
+
+// runningTask 是开启TaskTracking的时候正在执行的任务的记录
+type runningTask struct {
+	goid  uint64
+	start time.Time
+}
+
+// PoolDump 是DumpState返回的pool的快照，可以直接序列化成JSON
+type PoolDump struct {
+	PoolStats
+
+	// 已经交给worker但还没有执行完的任务的数量，见InFlight
+	InFlight int `json:"inFlight"`
+
+	// pool是否已经关闭
+	Closed bool `json:"closed"`
+
+	// 开启TaskTracking的时候正在执行的任务，按开始执行的时间排序，没有开启的时候为nil
+	Tasks []TaskDump `json:"tasks,omitempty"`
+}
+
+// TaskDump 是一个正在执行的任务
+type TaskDump struct {
+	// 执行任务的worker的id
+	WorkerID uint64 `json:"workerId"`
+
+	// 任务开始执行的时间
+	StartedAt time.Time `json:"startedAt"`
+
+	// 通过SubmitEnvelope提交的任务的Labels，没有的时候为nil
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// DumpState 返回pool的快照，用来做诊断接口。开启TaskTracking的时候还包括正在执行的任务的开始时间和标签，
+// 计数和任务列表在持有记录任务的锁的时候一起读取，返回的数据都是拷贝，调用者可以随意修改和序列化
+func (p *Pool) DumpState() PoolDump {
+	p.runningLock.Lock()
+	defer p.runningLock.Unlock()
+	dump := PoolDump{
+		PoolStats: p.Stats(),
+		InFlight:  p.InFlight(),
+		Closed:    p.IsClosed(),
+	}
+	if !p.options.TaskTracking {
+		return dump
+	}
+	dump.Tasks = make([]TaskDump, 0, len(p.runningTasks))
+	for id, task := range p.runningTasks {
+		td := TaskDump{WorkerID: id, StartedAt: task.start}
+		if v, ok := taskValues.Load(task.goid); ok && v.(*taskLocals).labels != nil {
+			labels := v.(*taskLocals).labels
+			td.Labels = make(map[string]string, len(labels))
+			for k, v := range labels {
+				td.Labels[k] = v
+			}
+		}
+		dump.Tasks = append(dump.Tasks, td)
+	}
+	sort.Slice(dump.Tasks, func(i, j int) bool {
+		return dump.Tasks[i].StartedAt.Before(dump.Tasks[j].StartedAt)
+	})
+	return dump
+}
+
+// beginTask 开启TaskTracking的时候在worker开始执行任务之前调用
+func (p *Pool) beginTask(workerID uint64) {
+	task := runningTask{goid: internal.GoID(), start: time.Now()}
+	p.runningLock.Lock()
+	if p.runningTasks == nil {
+		p.runningTasks = make(map[uint64]runningTask)
+	}
+	p.runningTasks[workerID] = task
+	p.runningLock.Unlock()
+}
+
+// endTask 开启TaskTracking的时候在任务结束（包括panic）之后调用
+func (p *Pool) endTask(workerID uint64) {
+	p.runningLock.Lock()
+	delete(p.runningTasks, workerID)
+	p.runningLock.Unlock()
+}
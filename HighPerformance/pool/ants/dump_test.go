@@ -0,0 +1,66 @@
+package ants
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpState(t *testing.T) {
+	p, err := NewPool(10, WithTaskTracking(true))
+	assert.NoError(t, err)
+	defer p.Release()
+
+	release := make(chan struct{})
+	before := time.Now()
+	for _, job := range []string{"import", "export"} {
+		assert.NoError(t, p.SubmitEnvelope(TaskEnvelope{
+			Task:   func() { <-release },
+			Labels: map[string]string{"job": job},
+		}))
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.NoError(t, p.Submit(func() { <-release }))
+
+	var dump PoolDump
+	eventually(t, func() bool {
+		dump = p.DumpState()
+		return len(dump.Tasks) == 3 && dump.Tasks[1].Labels != nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, dump.Running)
+	assert.Equal(t, 3, dump.InFlight)
+	assert.False(t, dump.Closed)
+	assert.Equal(t, map[string]string{"job": "import"}, dump.Tasks[0].Labels)
+	assert.Equal(t, map[string]string{"job": "export"}, dump.Tasks[1].Labels)
+	assert.Nil(t, dump.Tasks[2].Labels)
+	for i, task := range dump.Tasks {
+		assert.False(t, task.StartedAt.Before(before))
+		assert.NotZero(t, task.WorkerID)
+		if i > 0 {
+			assert.False(t, task.StartedAt.Before(dump.Tasks[i-1].StartedAt), "tasks should be sorted by start time")
+		}
+	}
+	data, err := json.Marshal(dump)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"labels":{"job":"export"}`)
+	assert.Contains(t, string(data), `"inFlight":`)
+	assert.Contains(t, string(data), `"startedAt":`)
+
+	close(release)
+	eventually(t, func() bool {
+		return len(p.DumpState().Tasks) == 0
+	}, time.Second, time.Millisecond)
+
+	// 没有开启TaskTracking的时候只有计数
+	p1, err := NewPool(10)
+	assert.NoError(t, err)
+	defer p1.Release()
+	block := make(chan struct{})
+	defer close(block)
+	assert.NoError(t, p1.Submit(func() { <-block }))
+	dump = p1.DumpState()
+	assert.Nil(t, dump.Tasks)
+	assert.Equal(t, 1, dump.Running)
+}
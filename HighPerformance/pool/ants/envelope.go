@@ -0,0 +1,49 @@
+package ants
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TaskEnvelope 把任务和它的元数据包装在一起，通过SubmitEnvelope提交
+type TaskEnvelope struct {
+	// Task 要执行的任务
+	Task func()
+
+	// Priority 任务的优先级，开启PreemptiveQueue的时候用来抢占等待的位置，见SubmitWithPriority
+	Priority int
+
+	// Labels 任务的标签，任务执行期间可以通过TaskLabels读取
+	Labels map[string]string
+
+	// DeadlineAfter 大于0的时候，提交之后超过这个时间还没有开始执行的任务会被跳过，计入Stats().ExpiredTasks
+	DeadlineAfter time.Duration
+}
+
+// SubmitEnvelope 提交一个TaskEnvelope，worker在执行之前先检查DeadlineAfter，再调用env.Task()
+func (p *Pool) SubmitEnvelope(env TaskEnvelope) error {
+	var deadline int64
+	if env.DeadlineAfter > 0 {
+		deadline = nanotime() + int64(env.DeadlineAfter)
+	}
+	return p.SubmitWithPriority(env.Priority, func() {
+		if deadline != 0 && nanotime() > deadline {
+			atomic.AddUint64(&p.expiredTasks, 1)
+			return
+		}
+		if env.Labels == nil {
+			env.Task()
+			return
+		}
+		runWithLocals(&taskLocals{labels: env.Labels}, env.Task)
+	})
+}
+
+// TaskLabels 在通过SubmitEnvelope提交的任务中返回它的Labels，不在这样的任务中调用时返回nil
+func TaskLabels() map[string]string {
+	locals := currentLocals()
+	if locals == nil {
+		return nil
+	}
+	return locals.labels
+}
@@ -0,0 +1,56 @@
+package ants
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitEnvelope(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	labels := make(chan map[string]string, 1)
+	err = p.SubmitEnvelope(TaskEnvelope{
+		Task:   func() { labels <- TaskLabels() },
+		Labels: map[string]string{"tenant": "a"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"tenant": "a"}, <-labels)
+	assert.Nil(t, TaskLabels(), "no labels should be found outside tasks")
+
+	// 同步的pool在调用者的goroutine中执行任务，可以精确控制开始执行的时间
+	p1, err := NewPool(0, WithSynchronous())
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p1.Release()
+	var ran bool
+	assert.NoError(t, p1.SubmitEnvelope(TaskEnvelope{Task: func() { ran = true }, DeadlineAfter: time.Minute}))
+	assert.True(t, ran, "task within its deadline should run")
+	assert.EqualValues(t, 0, p1.Stats().ExpiredTasks)
+}
+
+func TestSubmitEnvelopeDeadline(t *testing.T) {
+	p, err := NewPool(1)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 手动控制的worker，拿到任务之后由测试决定什么时候执行
+	p.incRunning()
+	defer p.decRunning()
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+
+	var ran int
+	for _, deadline := range []time.Duration{10 * time.Millisecond, time.Minute} {
+		p.revertWorker(w)
+		assert.NoError(t, p.SubmitEnvelope(TaskEnvelope{
+			Task:          func() { ran++ },
+			DeadlineAfter: deadline,
+		}))
+		time.Sleep(20 * time.Millisecond)
+		(<-w.task)()
+	}
+	assert.EqualValues(t, 1, ran, "only the task within its deadline should run")
+	assert.EqualValues(t, 1, p.Stats().ExpiredTasks, "task past its deadline should be skipped")
+}
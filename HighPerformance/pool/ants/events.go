@@ -0,0 +1,50 @@
+package ants
+
+import "time"
+
+// eventChanCap 是事件channel的缓冲大小，channel满了之后新的事件会被丢弃
+const eventChanCap = 64
+
+// EventType 是pool事件的类型
+type EventType int
+
+const (
+	// EventScaleUp 自动扩容
+	EventScaleUp EventType = iota + 1
+
+	// EventScaleDown 自动缩容
+	EventScaleDown
+
+	// EventUnhealthy 定期健康检查失败
+	EventUnhealthy
+)
+
+// Event 是pool运行过程中发生的事件
+type Event struct {
+	Type EventType
+
+	// 事件发生的时间
+	Time time.Time
+
+	// 变化前后的容量，扩缩容事件时有效
+	OldCap int
+	NewCap int
+
+	// 健康检查失败的原因，EventUnhealthy时有效
+	Err error
+}
+
+// Watch 返回接收pool事件的channel，所有调用者拿到的是同一个channel，
+// 事件是非阻塞发送的，没有及时接收的话channel满了之后的事件会被丢弃
+func (p *Pool) Watch() <-chan Event {
+	return p.events
+}
+
+// emit 非阻塞地发送一个事件
+func (p *Pool) emit(e Event) {
+	e.Time = time.Now()
+	select {
+	case p.events <- e:
+	default:
+	}
+}
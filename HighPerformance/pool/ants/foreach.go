@@ -0,0 +1,106 @@
+package ants
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ForEach 在p的worker上对切片items中的每个元素调用一次f(i, items[i])，阻塞到全部处理完为止，items不是切片的时候返回ErrNotSlice。
+// 每个下标只会被处理一次，所以f可以直接修改items[i]（item是元素的拷贝，修改需要通过原来的切片）。
+// 下标被分成不超过pool容量的若干段，每段提交一个任务，减少提交的开销，也不会占用超过容量的worker；容量不限制的pool按GOMAXPROCS分段。
+// f中的panic会被recover并计入Panics()，不影响其他元素的处理，最后合并成*ForEachError返回；提交失败的时候等已经提交的段处理完之后返回提交的错误
+func ForEach(p *Pool, items interface{}, f func(i int, item interface{})) error {
+	if items == nil {
+		return nil
+	}
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return ErrNotSlice
+	}
+	return forEachIndex(p, v.Len(), func(i int) {
+		f(i, v.Index(i).Interface())
+	})
+}
+
+// forEachIndex 是ForEach的实现，对[0, n)中的每个下标调用一次f
+func forEachIndex(p *Pool, n int, f func(i int)) error {
+	if n <= 0 {
+		return nil
+	}
+	chunks := p.Cap()
+	if chunks <= 0 {
+		chunks = runtime.GOMAXPROCS(0)
+	}
+	if chunks > n {
+		chunks = n
+	}
+	size := (n + chunks - 1) / chunks
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		panics map[int]*PanicError
+		err    error
+	)
+	call := func(i int) {
+		defer func() {
+			if r := recover(); r != nil {
+				p.recordPanic(r)
+				lock.Lock()
+				if panics == nil {
+					panics = make(map[int]*PanicError)
+				}
+				panics[i] = &PanicError{Value: r}
+				lock.Unlock()
+			}
+		}()
+		f(i)
+	}
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		lo, hi := start, end
+		wg.Add(1)
+		if err = p.Submit(func() {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				call(i)
+			}
+		}); err != nil {
+			wg.Done()
+			break
+		}
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if len(panics) > 0 {
+		return &ForEachError{Panics: panics}
+	}
+	return nil
+}
+
+// ForEachError 合并了ForEach中发生的panic，key为发生panic的下标
+type ForEachError struct {
+	Panics map[int]*PanicError
+}
+
+func (e *ForEachError) Error() string {
+	indexes := make([]int, 0, len(e.Panics))
+	for i := range e.Panics {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	msgs := make([]string, len(indexes))
+	for j, i := range indexes {
+		msgs[j] = fmt.Sprintf("item %d: %v", i, e.Panics[i])
+	}
+	return strings.Join(msgs, "; ")
+}
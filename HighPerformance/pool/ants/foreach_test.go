@@ -0,0 +1,61 @@
+package ants
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEach(t *testing.T) {
+	p, err := NewPool(4)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	var calls, maxRunning int32
+	err = ForEach(p, items, func(i int, item interface{}) {
+		atomic.AddInt32(&calls, 1)
+		if r := int32(p.Running()); r > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, r)
+		}
+		items[i] = item.(int) * 2
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(items), calls)
+	assert.True(t, atomic.LoadInt32(&maxRunning) <= 4)
+	for i, v := range items {
+		assert.Equal(t, i*2, v)
+	}
+	assert.NoError(t, ForEach(p, []string{}, nil))
+	assert.NoError(t, ForEach(p, nil, nil))
+	assert.Equal(t, ErrNotSlice, ForEach(p, 10, func(int, interface{}) {}))
+}
+
+func TestForEachPanics(t *testing.T) {
+	p, err := NewPool(4)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	var calls int32
+	err = ForEach(p, make([]struct{}, 100), func(i int, _ interface{}) {
+		atomic.AddInt32(&calls, 1)
+		if i%40 == 3 {
+			panic(i)
+		}
+	})
+	assert.EqualValues(t, 100, calls, "panics should not stop other items")
+	var feErr *ForEachError
+	assert.True(t, errors.As(err, &feErr))
+	assert.Len(t, feErr.Panics, 3)
+	assert.Equal(t, 43, feErr.Panics[43].Value)
+	assert.Equal(t, "item 3: task panicked: 3; item 43: task panicked: 43; item 83: task panicked: 83", err.Error())
+	assert.EqualValues(t, 3, p.Panics())
+
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, ForEach(p, []int{1}, func(int, interface{}) {}))
+}
@@ -0,0 +1,75 @@
+package ants
+
+import (
+	"context"
+	"time"
+)
+
+// defaultProbeTimeout 没有设置ProbeTimeout时HealthCheck的探测超时时间
+const defaultProbeTimeout = time.Second
+
+// HealthCheck 提交一个空的探测任务，并等待它在ProbeTimeout内执行完成，超时的时候返回ErrUnhealthy，
+// 可以用来发现所有worker都卡住了的pool（这时Running()看起来仍然是正常的），例如作为Kubernetes的存活探针。
+// pool已经关闭的时候返回ErrPoolClosed，ctx先结束的时候返回ctx.Err()
+func (p *Pool) HealthCheck(ctx context.Context) error {
+	if p.IsClosed() {
+		return ErrPoolClosed
+	}
+	timeout := p.options.ProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	// 借助SubmitBatchCtx，探测超时的时候阻塞在等待worker上的提交也能立刻返回
+	switch err := p.SubmitBatchCtx(probeCtx, []func(){func() { close(done) }})[0]; err {
+	case nil:
+	case ErrContextCancelled:
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrUnhealthy
+	case ErrPoolOverload:
+		// 没有空闲的worker，探测任务根本无法执行
+		return ErrUnhealthy
+	default:
+		return err
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-probeCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrUnhealthy
+	}
+}
+
+// StartHealthProbe 启动一个goroutine每隔interval执行一次HealthCheck，失败的时候记录日志并发出EventUnhealthy事件，
+// pool关闭或者调用返回的函数之后停止
+func (p *Pool) StartHealthProbe(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if p.IsClosed() {
+				return
+			}
+			if err := p.HealthCheck(ctx); err == ErrUnhealthy {
+				p.options.Logger.Printf("pool health check failed: %v\n", err)
+				p.emit(Event{Type: EventUnhealthy, Err: err})
+			}
+		}
+	}()
+	return cancel
+}
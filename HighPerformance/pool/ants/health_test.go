@@ -0,0 +1,59 @@
+package ants
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck(t *testing.T) {
+	p, err := NewPool(10, WithProbeTimeout(50*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 所有的worker都卡住
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < p.Cap(); i++ {
+		assert.NoError(t, p.Submit(func() { <-block }))
+	}
+	assert.Equal(t, ErrUnhealthy, p.HealthCheck(context.Background()), "stuck pool should be unhealthy")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, p.HealthCheck(ctx))
+
+	p0, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p0.Release()
+	assert.NoError(t, p0.HealthCheck(context.Background()), "idle pool should be healthy")
+
+	p1, err := NewPool(1, WithNonblocking(true), WithProbeTimeout(50*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	assert.NoError(t, p1.Submit(func() { <-block }))
+	assert.Equal(t, ErrUnhealthy, p1.HealthCheck(context.Background()), "full nonblocking pool should be unhealthy")
+	p1.Release()
+	assert.Equal(t, ErrPoolClosed, p1.HealthCheck(context.Background()))
+}
+
+func TestStartHealthProbe(t *testing.T) {
+	p, err := NewPool(1, WithProbeTimeout(20*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	block := make(chan struct{})
+	defer close(block)
+	assert.NoError(t, p.Submit(func() { <-block }))
+
+	stop := p.StartHealthProbe(10 * time.Millisecond)
+	defer stop()
+	select {
+	case e := <-p.Watch():
+		assert.Equal(t, EventUnhealthy, e.Type)
+		assert.Equal(t, ErrUnhealthy, e.Err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no unhealthy event emitted")
+	}
+}
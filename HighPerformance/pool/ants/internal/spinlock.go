@@ -5,26 +5,12 @@
 package internal
 
 import (
-	"runtime"
 	"sync"
-	"sync/atomic"
-)
-
-// 实现了locker接口
-type spinLock uint32
-
-func (sl *spinLock) Lock() {
-	for !atomic.CompareAndSwapUint32((*uint32)(sl), 0, 1) {
-		// 如果没有获取到锁，就让出执行权
-		runtime.Gosched()
-	}
-}
 
-func (sl *spinLock) Unlock() {
-	atomic.StoreUint32((*uint32)(sl), 0)
-}
+	"github.com/panjf2000/ants/v2/spinlock"
+)
 
 // NewSpinLock instantiates a spin-lock.
 func NewSpinLock() sync.Locker {
-	return new(spinLock)
+	return spinlock.New()
 }
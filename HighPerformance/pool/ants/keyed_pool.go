@@ -0,0 +1,100 @@
+package ants
+
+import "sync"
+
+// KeyedPool 在Pool的基础上保证相同key的任务按提交的顺序一个接一个地执行，不同key的任务并发执行，并发数受pool的容量限制
+type KeyedPool struct {
+	pool *Pool
+
+	// lock 保护lanes
+	lock sync.Mutex
+
+	// lanes 存放每个key还在排队的任务，key存在说明已经有一个worker在执行这个key的任务
+	lanes map[string][]func()
+}
+
+// NewKeyedPool 创建一个KeyedPool实例
+func NewKeyedPool(size int, options ...Option) (*KeyedPool, error) {
+	p, err := NewPool(size, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyedPool{
+		pool:  p,
+		lanes: make(map[string][]func()),
+	}, nil
+}
+
+// SubmitKeyedSerial 提交一个任务，相同key的任务按照提交的顺序串行执行
+func (kp *KeyedPool) SubmitKeyedSerial(key string, task func()) error {
+	if kp.pool.IsClosed() {
+		return ErrPoolClosed
+	}
+	kp.lock.Lock()
+	if q, ok := kp.lanes[key]; ok {
+		// 这个key已经有worker在执行了，排队等它执行
+		kp.lanes[key] = append(q, task)
+		kp.lock.Unlock()
+		return nil
+	}
+	kp.lanes[key] = nil
+	kp.lock.Unlock()
+	return kp.dispatch(key, task)
+}
+
+// Pool 返回底层的pool
+func (kp *KeyedPool) Pool() *Pool {
+	return kp.pool
+}
+
+// Release 关闭底层的pool
+func (kp *KeyedPool) Release() {
+	kp.pool.Release()
+}
+
+// dispatch 把key的任务交给一个worker执行
+func (kp *KeyedPool) dispatch(key string, task func()) error {
+	err := kp.pool.Submit(func() { kp.runLane(key, task) })
+	if err == nil {
+		return nil
+	}
+	// 提交失败的时候，已经在排队的任务尝试换一个worker执行，全部失败的话这些任务会被丢弃
+	for next := kp.next(key); next != nil; next = kp.next(key) {
+		t := next
+		if kp.pool.Submit(func() { kp.runLane(key, t) }) == nil {
+			break
+		}
+	}
+	return err
+}
+
+// runLane 依次执行key的所有任务，直到队列为空
+func (kp *KeyedPool) runLane(key string, task func()) {
+	defer func() {
+		// task不为nil说明任务发生了panic，剩下的任务换一个worker继续执行，panic仍然交给pool处理
+		if task != nil {
+			if next := kp.next(key); next != nil {
+				go func() { _ = kp.dispatch(key, next) }()
+			}
+		}
+	}()
+	for task != nil {
+		task()
+		task = kp.next(key)
+	}
+}
+
+// next 取出key的下一个任务，队列为空的时候删除这个key并返回nil
+func (kp *KeyedPool) next(key string) func() {
+	kp.lock.Lock()
+	defer kp.lock.Unlock()
+	q := kp.lanes[key]
+	if len(q) == 0 {
+		delete(kp.lanes, key)
+		return nil
+	}
+	task := q[0]
+	q[0] = nil
+	kp.lanes[key] = q[1:]
+	return task
+}
@@ -0,0 +1,68 @@
+package ants
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedPoolSerial(t *testing.T) {
+	kp, err := NewKeyedPool(10)
+	assert.NoErrorf(t, err, "create KeyedPool failed: %v", err)
+	defer kp.Release()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		order = make(map[string][]int)
+	)
+	keys := []string{"a", "b", "c"}
+	for i := 0; i < 100; i++ {
+		for _, key := range keys {
+			i, key := i, key
+			wg.Add(1)
+			err := kp.SubmitKeyedSerial(key, func() {
+				defer wg.Done()
+				mu.Lock()
+				order[key] = append(order[key], i)
+				mu.Unlock()
+			})
+			assert.NoError(t, err, "submit keyed task shouldn't return error")
+		}
+	}
+	wg.Wait()
+	for _, key := range keys {
+		assert.Len(t, order[key], 100, "all tasks of key %s should be executed", key)
+		for i, v := range order[key] {
+			assert.Equalf(t, i, v, "tasks of key %s should be executed in submission order", key)
+		}
+	}
+}
+
+func TestKeyedPoolConcurrentKeys(t *testing.T) {
+	kp, err := NewKeyedPool(10)
+	assert.NoErrorf(t, err, "create KeyedPool failed: %v", err)
+	defer kp.Release()
+
+	// a的任务要等b的任务执行之后才能结束，如果不同的key不是并发执行的就会超时
+	ch := make(chan struct{})
+	done := make(chan struct{})
+	_ = kp.SubmitKeyedSerial("a", func() {
+		select {
+		case <-ch:
+			close(done)
+		case <-time.After(time.Second):
+		}
+	})
+	_ = kp.SubmitKeyedSerial("b", func() { close(ch) })
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("tasks with distinct keys should run concurrently")
+	}
+
+	kp.Release()
+	assert.Equal(t, ErrPoolClosed, kp.SubmitKeyedSerial("a", demoFunc))
+}
@@ -0,0 +1,20 @@
+package ants
+
+import "github.com/panjf2000/ants/v2/internal"
+
+// WorkerLocal 在任务中返回当前worker goroutine的本地对象，第一次调用的时候通过WorkerLocalInit创建，
+// 同一个goroutine上之后的任务拿到的都是同一个对象，适合保存创建开销大、可以在任务之间复用的资源（比如gzip.Writer）。
+// 对象只会被这个goroutine访问，不需要加锁；没有设置WorkerLocalInit或者不在任务中调用的时候返回nil
+func WorkerLocal() interface{} {
+	v, ok := taskWorkers.Load(internal.GoID())
+	if !ok {
+		return nil
+	}
+	w := v.(*goWorker)
+	if w.local == nil {
+		if init := w.pool.options.WorkerLocalInit; init != nil {
+			w.local = init()
+		}
+	}
+	return w.local
+}
@@ -0,0 +1,44 @@
+package ants
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerLocal(t *testing.T) {
+	p, err := NewPool(10, WithWorkerLocalInit(func() interface{} {
+		return new(int)
+	}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+	assert.Nil(t, WorkerLocal(), "WorkerLocal outside of a task should return nil")
+
+	// 和worker的执行循环一样，在同一个goroutine上执行多个任务
+	runTasks := func(w *goWorker, n int) (locals []*int) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < n; i++ {
+				w.injectContext()
+				local := WorkerLocal().(*int)
+				*local++
+				locals = append(locals, local)
+			}
+			w.clearContext()
+		}()
+		<-done
+		return
+	}
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	locals := runTasks(w, 3)
+	assert.Same(t, locals[0], locals[1], "tasks on the same worker should share the local")
+	assert.Same(t, locals[0], locals[2])
+	assert.Equal(t, 3, *locals[0])
+
+	// worker退出之后本地对象被丢弃，新的goroutine会重新创建
+	assert.Nil(t, w.local)
+	other := runTasks(w, 1)
+	assert.False(t, locals[0] == other[0], "a new worker goroutine should get a new local")
+	assert.Equal(t, 1, *other[0])
+}
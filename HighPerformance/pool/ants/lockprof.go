@@ -0,0 +1,29 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// profiledLock 包装pool.lock，统计等待获取锁的时间，开启LockProfiling的时候使用
+type profiledLock struct {
+	// 64位的原子操作要求8字节对齐，放在最前面
+	waitNanos    int64
+	acquisitions uint64
+
+	sync.Locker
+}
+
+// Lock 获取锁并累计等待的时间
+func (l *profiledLock) Lock() {
+	start := nanotime()
+	l.Locker.Lock()
+	atomic.AddInt64(&l.waitNanos, nanotime()-start)
+	atomic.AddUint64(&l.acquisitions, 1)
+}
+
+// stats 返回累计的等待时间和获取锁的次数
+func (l *profiledLock) stats() (time.Duration, uint64) {
+	return time.Duration(atomic.LoadInt64(&l.waitNanos)), atomic.LoadUint64(&l.acquisitions)
+}
@@ -0,0 +1,77 @@
+package ants
+
+import "context"
+
+// SubmitWithMemEstimate 提交一个估计会占用bytes字节内存的任务，设置了MemoryBudget的时候，
+// 只有正在执行和等待执行的任务估计的内存加上bytes不超过预算才会提交，否则等待别的任务完成，非阻塞的pool返回ErrPoolOverload；
+// 任务结束（包括panic）的时候归还它的预算。没有设置MemoryBudget的时候和Submit一样
+func (p *Pool) SubmitWithMemEstimate(bytes int64, task func()) error {
+	budget := p.options.MemoryBudget
+	if budget <= 0 {
+		return p.Submit(task)
+	}
+	if bytes < 0 || bytes > budget {
+		return ErrInvalidMemEstimate
+	}
+	if dropped, err := p.admit(); dropped || err != nil {
+		return err
+	}
+	if err := p.acquireMem(bytes); err != nil {
+		return err
+	}
+	run := func() {
+		defer p.releaseMem(bytes)
+		task()
+	}
+	if p.options.Synchronous {
+		p.runInline(run)
+		return nil
+	}
+	run, untrack := p.trackPending(task, run)
+	w, cancelled := p.retrieveWorkerOrCancel(context.Background())
+	if w == nil {
+		untrack()
+		p.releaseMem(bytes)
+		if cancelled {
+			return ErrTaskCancelled
+		}
+		if p.IsClosed() {
+			return ErrPoolClosed
+		}
+		return p.overflow(task)
+	}
+	w.dispatch(run)
+	return nil
+}
+
+// MemoryInUse 返回通过SubmitWithMemEstimate提交、还没有结束的任务估计占用的内存之和
+func (p *Pool) MemoryInUse() int64 {
+	p.memLock.Lock()
+	defer p.memLock.Unlock()
+	return p.memInUse
+}
+
+// acquireMem 占用bytes字节的内存预算，预算不够的时候等待，非阻塞的pool返回ErrPoolOverload，等待中pool被关闭的话返回ErrPoolClosed
+func (p *Pool) acquireMem(bytes int64) error {
+	p.memLock.Lock()
+	defer p.memLock.Unlock()
+	for p.memInUse+bytes > p.options.MemoryBudget {
+		if p.isNonblocking() {
+			return ErrPoolOverload
+		}
+		if p.IsClosed() {
+			return ErrPoolClosed
+		}
+		p.memCond.Wait()
+	}
+	p.memInUse += bytes
+	return nil
+}
+
+// releaseMem 归还bytes字节的内存预算，唤醒所有等待的提交者，它们需要的内存大小不同，由它们自己重新检查
+func (p *Pool) releaseMem(bytes int64) {
+	p.memLock.Lock()
+	p.memInUse -= bytes
+	p.memCond.Broadcast()
+	p.memLock.Unlock()
+}
@@ -0,0 +1,87 @@
+package ants
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitWithMemEstimate(t *testing.T) {
+	_, err := NewPool(10, WithMemoryBudget(-1))
+	assert.EqualError(t, err, ErrInvalidPoolConfig.Error())
+
+	p, err := NewPool(10, WithMemoryBudget(100))
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.Equal(t, ErrInvalidMemEstimate, p.SubmitWithMemEstimate(101, demoFunc))
+	assert.Equal(t, ErrInvalidMemEstimate, p.SubmitWithMemEstimate(-1, demoFunc))
+
+	release := make(chan struct{})
+	assert.NoError(t, p.SubmitWithMemEstimate(60, func() { <-release }))
+	assert.NoError(t, p.SubmitWithMemEstimate(40, func() { <-release }))
+	assert.EqualValues(t, 100, p.MemoryInUse())
+
+	// 还有空闲的worker，但是预算已经用完，要等前面的任务结束才能提交
+	var admitted int32
+	done := make(chan error)
+	go func() {
+		done <- p.SubmitWithMemEstimate(50, func() { atomic.StoreInt32(&admitted, 1) })
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&admitted))
+	assert.True(t, p.Running() < p.Cap())
+	close(release)
+	assert.NoError(t, <-done)
+	eventually(t, func() bool {
+		return atomic.LoadInt32(&admitted) == 1 && p.MemoryInUse() == 0
+	}, time.Second, time.Millisecond)
+
+	// 很多小任务不受大任务的限制
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, p.SubmitWithMemEstimate(1, demoFunc))
+	}
+
+	// 等待预算的时候pool被关闭
+	block := make(chan struct{})
+	defer close(block)
+	assert.NoError(t, p.SubmitWithMemEstimate(100, func() { <-block }))
+	go func() {
+		done <- p.SubmitWithMemEstimate(1, demoFunc)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	p.Release()
+	assert.Equal(t, ErrPoolClosed, <-done)
+
+	// 非阻塞的pool预算不够的时候直接拒绝
+	np, err := NewPool(10, WithMemoryBudget(10), WithNonblocking(true))
+	assert.NoError(t, err)
+	defer np.Release()
+	wait := make(chan struct{})
+	defer close(wait)
+	assert.NoError(t, np.SubmitWithMemEstimate(10, func() { <-wait }))
+	assert.Equal(t, ErrPoolOverload, np.SubmitWithMemEstimate(1, demoFunc))
+	assert.EqualValues(t, 10, np.MemoryInUse())
+
+	// 等待worker的时候pool被关闭，返回ErrPoolClosed而不是交给OverflowPool
+	secondary, err := NewPool(10)
+	assert.NoError(t, err)
+	defer secondary.Release()
+	op, err := NewPool(1, WithMemoryBudget(100), WithOverflowPool(secondary))
+	assert.NoError(t, err)
+	busy := make(chan struct{})
+	assert.NoError(t, op.SubmitWithMemEstimate(1, func() { <-busy }))
+	var overflowed int32
+	go func() {
+		done <- op.SubmitWithMemEstimate(1, func() { atomic.StoreInt32(&overflowed, 1) })
+	}()
+	for atomic.LoadInt32(&op.blockingNum) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	op.Release()
+	close(busy)
+	assert.Equal(t, ErrPoolClosed, <-done)
+	eventually(t, func() bool { return op.MemoryInUse() == 0 }, time.Second, time.Millisecond)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&overflowed), "task should not go to the overflow pool after Release")
+}
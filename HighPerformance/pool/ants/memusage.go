@@ -0,0 +1,88 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// 估算MemoryUsage时使用的runtime内部结构的大概大小
+const (
+	// hchanSize channel头部(runtime.hchan)的大小
+	hchanSize = 96
+	// goroutineSize goroutine的初始栈加上runtime.g的大小
+	goroutineSize = 2<<10 + 400
+	// spinLockSize internal.NewSpinLock分配的锁的大小
+	spinLockSize = 8
+)
+
+// MemStats 是MemoryUsage估算的pool自身占用的内存（字节），按用途分开统计
+type MemStats struct {
+	// Pool和Options结构体以及事件channel
+	Pool int64 `json:"pool"`
+
+	// 每个还没有退出的worker的goWorker结构体
+	Workers int64 `json:"workers"`
+
+	// 每个还没有退出的worker的任务channel，包括channel头部和缓冲
+	TaskChans int64 `json:"taskChans"`
+
+	// 每个还没有退出的worker的goroutine的栈和runtime.g
+	Goroutines int64 `json:"goroutines"`
+
+	// 存放空闲worker的队列（WithPreAlloc时是环形队列）分配的槽位
+	Queue int64 `json:"queue"`
+
+	// pool.lock和各个sync.Cond
+	Locks int64 `json:"locks"`
+
+	// PinnedPool创建的子pool的Total之和
+	Pinned int64 `json:"pinned"`
+
+	// 以上各项之和
+	Total int64 `json:"total"`
+}
+
+// MemoryUsage 估算pool自身占用的内存，不包括任务本身分配的内存，用来给容器留出pool的开销。
+// 只是估算，和runtime.MemStats统计的实际增长的差别在两倍之内
+func (p *Pool) MemoryUsage() MemStats {
+	var s MemStats
+	s.Pool = int64(unsafe.Sizeof(*p)) + int64(unsafe.Sizeof(*p.options))
+	s.Pool += hchanSize + int64(cap(p.events))*int64(unsafe.Sizeof(Event{}))
+	s.Locks = spinLockSize + 3*int64(unsafe.Sizeof(sync.Cond{}))
+
+	p.lock.Lock()
+	slots := workerArraySlots(p.workers)
+	p.lock.Unlock()
+	s.Queue = int64(slots) * int64(unsafe.Sizeof((*goWorker)(nil)))
+
+	alive := int64(atomic.LoadInt32(&p.alive))
+	s.Workers = alive * int64(unsafe.Sizeof(goWorker{}))
+	s.TaskChans = alive * (hchanSize + int64(p.taskChanCap)*int64(unsafe.Sizeof(func() {})))
+	s.Goroutines = alive * goroutineSize
+
+	p.pinnedLock.Lock()
+	pinned := make([]*Pool, 0, len(p.pinned))
+	for _, sub := range p.pinned {
+		pinned = append(pinned, sub)
+	}
+	p.pinnedLock.Unlock()
+	for _, sub := range pinned {
+		s.Pinned += sub.MemoryUsage().Total
+	}
+
+	s.Total = s.Pool + s.Workers + s.TaskChans + s.Goroutines + s.Queue + s.Locks + s.Pinned
+	return s
+}
+
+// workerArraySlots 返回wa分配的槽位数量，需要持有pool.lock
+func workerArraySlots(wa WorkerArray) int {
+	switch q := wa.(type) {
+	case *workerStack:
+		return cap(q.items) + cap(q.expiry)
+	case *loopQueue:
+		return cap(q.items) + cap(q.expiry)
+	default:
+		return wa.Len()
+	}
+}
@@ -0,0 +1,45 @@
+package ants
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// heapAndStack 在GC之后返回堆和goroutine栈占用的内存
+func heapAndStack() int64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return int64(ms.HeapAlloc) + int64(ms.StackInuse)
+}
+
+func TestMemoryUsage(t *testing.T) {
+	const n = 2000
+	block := make(chan struct{})
+	wait := func() { <-block }
+
+	before := heapAndStack()
+	p, err := NewPool(n, WithPreAlloc(true), WithExpiryDuration(time.Hour))
+	assert.NoError(t, err)
+	defer p.Release()
+	defer close(block)
+	for i := 0; i < n; i++ {
+		assert.NoError(t, p.Submit(wait))
+	}
+	assert.Equal(t, n, p.Running())
+	delta := heapAndStack() - before
+
+	usage := p.MemoryUsage()
+	assert.Equal(t, usage.Pool+usage.Workers+usage.TaskChans+usage.Goroutines+usage.Queue+usage.Locks+usage.Pinned, usage.Total)
+	assert.True(t, usage.Workers > 0 && usage.TaskChans > 0 && usage.Goroutines > 0 && usage.Queue > 0 && usage.Locks > 0)
+	assert.True(t, usage.Total*2 >= delta && usage.Total <= delta*2,
+		"estimate %d should be within 2x of the MemStats delta %d", usage.Total, delta)
+
+	// PinnedPool创建的子pool也计入
+	sub, err := p.PinnedPool("a")
+	assert.NoError(t, err)
+	assert.Equal(t, sub.MemoryUsage().Total, p.MemoryUsage().Pinned)
+}
@@ -0,0 +1,98 @@
+package ants
+
+import "time"
+
+// PoolMetrics 是WatchMetrics推送的某一时刻的统计数据
+type PoolMetrics struct {
+	PoolStats
+
+	// 采样的时间
+	Time time.Time
+}
+
+// metricsWatcher 是一个WatchMetrics的接收者
+type metricsWatcher struct {
+	ch       chan PoolMetrics
+	interval time.Duration
+	last     time.Time
+}
+
+// WatchMetrics 返回一个每隔interval收到一次统计数据的channel，所有的接收者共用一个goroutine，它在第一次调用的时候才启动。
+// 发送是非阻塞的，来不及接收的数据会被丢弃；调用CancelWatch或者pool关闭的时候channel会被关闭
+func (p *Pool) WatchMetrics(interval time.Duration) <-chan PoolMetrics {
+	ch := make(chan PoolMetrics, 1)
+	p.watchLock.Lock()
+	defer p.watchLock.Unlock()
+	if p.IsClosed() || interval <= 0 {
+		close(ch)
+		return ch
+	}
+	if p.watchers == nil {
+		p.watchers = make(map[<-chan PoolMetrics]*metricsWatcher)
+	}
+	p.watchers[ch] = &metricsWatcher{ch: ch, interval: interval, last: time.Now()}
+	p.restartMetricsPush()
+	return ch
+}
+
+// CancelWatch 取消WatchMetrics返回的ch，ch会被关闭，调用者应该把ch中剩下的数据读完或者直接丢弃它
+func (p *Pool) CancelWatch(ch <-chan PoolMetrics) {
+	p.watchLock.Lock()
+	defer p.watchLock.Unlock()
+	if w, ok := p.watchers[ch]; ok {
+		delete(p.watchers, ch)
+		close(w.ch)
+		p.restartMetricsPush()
+	}
+}
+
+// restartMetricsPush 按照所有接收者中最小的间隔重新启动推送指标的goroutine，间隔没有变化的时候什么也不做，
+// 没有接收者的时候停止它，需要持有watchLock
+func (p *Pool) restartMetricsPush() {
+	var interval time.Duration
+	for _, w := range p.watchers {
+		if interval == 0 || w.interval < interval {
+			interval = w.interval
+		}
+	}
+	if interval == p.watchInterval && p.watchStop != nil {
+		return
+	}
+	if p.watchStop != nil {
+		close(p.watchStop)
+		p.watchStop = nil
+	}
+	p.watchInterval = interval
+	if interval > 0 {
+		p.watchStop = make(chan struct{})
+		p.every(interval, p.watchStop, p.pushMetrics)
+	}
+}
+
+// pushMetrics 把now时刻的统计数据推送给到了自己间隔的接收者
+func (p *Pool) pushMetrics(now time.Time) {
+	m := PoolMetrics{PoolStats: p.Stats(), Time: now}
+	p.watchLock.Lock()
+	defer p.watchLock.Unlock()
+	for _, w := range p.watchers {
+		if now.Sub(w.last) < w.interval {
+			continue
+		}
+		w.last = now
+		select {
+		case w.ch <- m:
+		default:
+		}
+	}
+}
+
+// closeWatchers 关闭所有接收者的channel并停止推送，在Release中调用
+func (p *Pool) closeWatchers() {
+	p.watchLock.Lock()
+	defer p.watchLock.Unlock()
+	for ch, w := range p.watchers {
+		delete(p.watchers, ch)
+		close(w.ch)
+	}
+	p.restartMetricsPush()
+}
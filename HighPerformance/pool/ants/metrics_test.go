@@ -0,0 +1,56 @@
+package ants
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchMetrics(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	fast := p.WatchMetrics(5 * time.Millisecond)
+	slow := p.WatchMetrics(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		m := <-fast
+		assert.EqualValues(t, 10, m.Capacity)
+		assert.False(t, m.Time.IsZero())
+	}
+	<-slow
+
+	p.CancelWatch(fast)
+	for range fast {
+	}
+	// 取消最快的接收者之后按剩下的最小间隔推送
+	<-slow
+	p.watchLock.Lock()
+	assert.Equal(t, 20*time.Millisecond, p.watchInterval)
+	p.watchLock.Unlock()
+
+	p.Release()
+	select {
+	case <-waitClosed(slow):
+	case <-time.After(time.Second):
+		t.Fatal("metrics channel should be closed when the pool is released")
+	}
+
+	_, ok := <-p.WatchMetrics(time.Millisecond)
+	assert.False(t, ok, "watching a released pool should return a closed channel")
+	p.watchLock.Lock()
+	assert.Nil(t, p.watchStop, "pushing should stop without watchers")
+	p.watchLock.Unlock()
+}
+
+// waitClosed 把ch中的数据读完，ch关闭之后关闭返回的channel
+func waitClosed(ch <-chan PoolMetrics) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
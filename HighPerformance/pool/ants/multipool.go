@@ -0,0 +1,99 @@
+package ants
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AggregateStatsKey 是MultiPool.Stats返回的所有pool合计数据的key
+const AggregateStatsKey = "*"
+
+// PoolSpec 描述MultiPool中一个pool的容量和配置
+type PoolSpec struct {
+	Size    int
+	Options []Option
+}
+
+// MultiPool 统一管理多个不同配置的pool，比如io、cpu、background各用一个pool
+type MultiPool struct {
+	pools map[string]*Pool
+}
+
+// NewMultiPool 根据specs为每个名字创建一个pool，任意一个创建失败的话会释放已经创建的pool
+func NewMultiPool(specs map[string]PoolSpec) (*MultiPool, error) {
+	mp := &MultiPool{pools: make(map[string]*Pool, len(specs))}
+	for name, spec := range specs {
+		p, err := NewPool(spec.Size, spec.Options...)
+		if err != nil {
+			for _, created := range mp.pools {
+				created.Release()
+			}
+			return nil, err
+		}
+		mp.pools[name] = p
+	}
+	return mp, nil
+}
+
+// Get 返回名字对应的pool，不存在的时候返回nil
+func (mp *MultiPool) Get(name string) *Pool {
+	return mp.pools[name]
+}
+
+// Stats 返回每个pool的统计数据，以及key为AggregateStatsKey的合计数据
+func (mp *MultiPool) Stats() map[string]PoolStats {
+	stats := make(map[string]PoolStats, len(mp.pools)+1)
+	var total PoolStats
+	for name, p := range mp.pools {
+		s := p.Stats()
+		stats[name] = s
+		total = total.add(s)
+	}
+	stats[AggregateStatsKey] = total
+	return stats
+}
+
+// ReleaseAll 并发地关闭所有的pool并等待它们的worker退出，ctx结束时还没有关闭完成的pool的错误会合并返回
+func (mp *MultiPool) ReleaseAll(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs = make(map[string]error)
+	)
+	for name, p := range mp.pools {
+		wg.Add(1)
+		go func(name string, p *Pool) {
+			defer wg.Done()
+			if err := p.ReleaseContext(ctx); err != nil {
+				lock.Lock()
+				errs[name] = err
+				lock.Unlock()
+			}
+		}(name, p)
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiPoolError{Errors: errs}
+}
+
+// MultiPoolError 合并了MultiPool中多个pool返回的错误，key为pool的名字
+type MultiPoolError struct {
+	Errors map[string]error
+}
+
+func (e *MultiPoolError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = name + ": " + e.Errors[name].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,50 @@
+package ants
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiPool(t *testing.T) {
+	mp, err := NewMultiPool(map[string]PoolSpec{
+		"io":         {Size: 10},
+		"cpu":        {Size: 2, Options: []Option{WithNonblocking(true)}},
+		"background": {Size: -1},
+	})
+	assert.NoErrorf(t, err, "create MultiPool failed: %v", err)
+	assert.Nil(t, mp.Get("unknown"))
+
+	ch := make(chan struct{})
+	cpu := mp.Get("cpu")
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, cpu.Submit(func() { <-ch }), "submit when pool is not full shouldn't return error")
+	}
+	assert.Equal(t, ErrPoolOverload, cpu.Submit(demoFunc), "options of the spec should take effect")
+
+	stats := mp.Stats()
+	assert.Len(t, stats, 4)
+	assert.EqualValues(t, 10, stats["io"].Capacity)
+	assert.EqualValues(t, 2, stats["cpu"].Running)
+	assert.EqualValues(t, -1, stats[AggregateStatsKey].Capacity, "aggregate capacity should be unlimited")
+	assert.EqualValues(t, 2, stats[AggregateStatsKey].Running)
+
+	// cpu中的任务还没有结束，关闭会超时
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = mp.ReleaseAll(ctx)
+	assert.Error(t, err, "release should time out while tasks are running")
+	mErr, ok := err.(*MultiPoolError)
+	assert.True(t, ok, "error should be a MultiPoolError")
+	assert.Len(t, mErr.Errors, 1)
+	assert.Equal(t, context.DeadlineExceeded, mErr.Errors["cpu"])
+	close(ch)
+	for _, name := range []string{"io", "cpu", "background"} {
+		assert.True(t, mp.Get(name).IsClosed(), "all pools should be released")
+	}
+
+	_, err = NewMultiPool(map[string]PoolSpec{"bad": {Size: 1, Options: []Option{WithExpiryDuration(-1)}}})
+	assert.Equal(t, ErrInvalidPoolExpiry, err)
+}
@@ -1,6 +1,9 @@
 package ants
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Option func(opts *Options)
 
@@ -37,6 +40,149 @@ type Options struct {
 
 	// Logger是一个用来记录日志信息的定制组件，如果没有设置就会使用log包中的默认的日志组件
 	Logger Logger
+
+	// 当为true的时候，不会启动定期清理过期worker的goroutine，worker会一直常驻
+	DisablePurge bool
+
+	// 当为true的时候，最近归还的一个worker会放在一个无锁的槽位中，Submit可以不加锁直接取到它，
+	// 适合提交→完成→提交这种来回的负载，只对Pool生效
+	HotWorker bool
+
+	// OnTaskDone 在每个任务执行完之后调用，dur是任务的执行时间，任务发生panic的时候recovered是panic的值，否则为nil
+	OnTaskDone func(dur time.Duration, recovered interface{})
+
+	// CPUAffinity 不为空的时候，每个worker都会锁定到一个OS线程上，并把线程轮流绑定到其中的一个CPU上，
+	// 只在Linux上生效，其它平台上只会锁定线程
+	CPUAffinity []int
+
+	// 为true的时候，每个worker都会在启动时调用runtime.LockOSThread独占一个OS线程，退出时再释放，
+	// 适合调用C代码或者依赖线程局部状态的任务，每个worker的开销会变大，不建议和不限制容量的pool一起使用
+	OSThreads bool
+
+	// 在PanicStormWindow时间内发生了超过PanicStormThreshold次panic的时候，pool进入降级状态，
+	// 之后的一个PanicStormWindow内Submit都会返回ErrPanicStorm，两个都大于0的时候才生效，只对Pool有效
+	PanicStormThreshold int
+	PanicStormWindow    time.Duration
+
+	// LoadShedder 不为nil的时候，每种提交方式在提交之前都先用当前的负载（执行任务的worker数/容量）询问它，
+	// 需要丢弃的时候任务被直接丢弃，Submit返回nil，丢弃的任务数计入Stats().DroppedTasks，只对容量有限的Pool有效
+	LoadShedder LoadShedder
+
+	// 为true的时候worker执行完一个任务就退出，每个任务都在新的goroutine中执行，
+	// 用来排查goroutine局部状态在任务之间泄漏的问题，会让pool失去复用goroutine的意义，不要在生产环境中使用
+	NoReuse bool
+
+	// MetricsSink 不为nil并且MetricsInterval大于0的时候，pool会启动一个goroutine每隔MetricsInterval把Stats()推送给它，
+	// Release的时候停止，Reboot的时候重新启动，只对Pool有效
+	MetricsInterval time.Duration
+	MetricsSink     func(PoolStats)
+
+	// 为true的时候，通过SubmitWithPriority提交的任务在pool已满并且阻塞的提交者达到MaxBlockingTasks的时候，
+	// 可以挤掉一个优先级更低的、还在等待worker的提交，被挤掉的提交返回ErrPreempted，它的任务交给RejectHandler，
+	// 已经开始执行的任务不会被抢占，只对Pool有效
+	PreemptiveQueue bool
+
+	// RejectHandler 接收被抢占而没有执行的任务
+	RejectHandler func(task func())
+
+	// Capacity 只在Pool.RebootWith中使用，不为0的时候作为重启之后的容量，小于0表示不限制，
+	// 为0的时候保持原来的容量，NewPool使用它的size参数
+	Capacity int
+
+	// 为true的时候统计等待获取pool.lock的时间，通过Stats的LockWait和LockAcquisitions查看，
+	// 为false的时候pool.lock不做任何包装，没有额外的开销，只对Pool有效
+	LockProfiling bool
+
+	// OnWorkerStart 在每个worker goroutine启动的时候调用一次，OnWorkerStop 在它退出的时候调用一次，
+	// 都在worker自己的goroutine中调用，workerID和SubmitTracked返回的id相同，可以用来初始化和清理每个goroutine独占的资源；
+	// 回调中的panic会被记录到日志，不会影响worker，只对Pool有效
+	OnWorkerStart func(workerID uint64)
+	OnWorkerStop  func(workerID uint64)
+
+	// 大于0的时候，执行时间超过MaxTaskDuration的任务所在的worker会被放弃：它不再计入Running()，
+	// pool会创建一个新的worker补上容量，卡住的goroutine继续运行，任务结束之后直接退出，放弃的次数计入Stats().AbandonedWorkers。
+	// Go没法终止goroutine，这是用泄漏一个goroutine换回pool的容量，只对Pool有效
+	MaxTaskDuration time.Duration
+
+	// Pool.Chan返回的channel的缓冲大小，为0的时候是不带缓冲的channel
+	ChanBufferSize int
+
+	// 为true的时候记录通过Submit提交、还没有开始执行的任务（阻塞在Submit上的和在worker的任务channel中等待的），
+	// 可以通过Pool.EachTask查看，每次提交会多一次内存分配，只对Pool有效
+	TrackPendingTasks bool
+
+	// 是否记录每个正在执行的任务的开始时间和标签，开启之后DumpState会列出这些任务，
+	// 每个任务开始和结束的时候都要加一次锁，只在需要诊断的时候开启，只对Pool有效
+	TaskTracking bool
+
+	// 大于0的时候记录最近CapHistory次容量的变化（Tune、AdjustCapacity、MigrateTo、Shrink、RebootWith），见Pool.CapHistory，只对Pool有效
+	CapHistory int
+
+	// NewPool的时候预先创建WarmWorkerCache个goWorker（连同它们的任务channel）放到workerCache中，
+	// 第一波突发的任务创建worker的时候不需要再分配内存，适合和PreAlloc一起用在很大的pool上。
+	// workerCache是sync.Pool，里面的对象在两次GC之后会被回收，所以只对pool刚创建之后的突发有效，只对Pool有效
+	WarmWorkerCache int
+
+	// 大于0的时候限制通过SubmitAfter和SubmitAfterMany提交、还没有到时间的任务数量，超过的时候返回ErrDelayQueueFull，
+	// 为0的时候不限制，只对Pool有效
+	MaxDelayedTasks int
+
+	// WorkerLocalInit 不为nil的时候，每个worker goroutine在任务中第一次调用WorkerLocal时用它创建一个只属于自己的对象，
+	// 之后同一个goroutine上的任务都拿到同一个对象，goroutine退出的时候丢弃，只对Pool有效
+	WorkerLocalInit func() interface{}
+
+	// NewPool创建pool（以及RebootWith重启）的时候预先启动的空闲worker的数量，不能超过容量，和PreAlloc不同的是不需要预先分配固定大小的队列，
+	// 之后pool仍然可以按需增长到容量；这些worker和其他空闲的worker一样会过期被清理，只对Pool有效
+	InitialWorkers int
+
+	// 大于0的时候限制每秒最多创建SpawnRateLimit个新的worker goroutine，避免冷启动时的突发流量一下子创建大量goroutine，
+	// 超出速率的提交者即使pool还没满也要等到允许创建为止，等待期间计入阻塞的提交者，非阻塞的pool直接拒绝；复用空闲的worker不受限制
+	SpawnRateLimit int
+
+	// 大于0的时候，通过SubmitWithMemEstimate提交的任务估计占用的内存（字节）之和不能超过MemoryBudget，和worker的数量无关，
+	// 超出的时候提交者等待别的任务完成释放预算，非阻塞的pool直接拒绝；等待内存预算的提交者不计入MaxBlockingTasks，只对Pool有效
+	MemoryBudget int64
+
+	// OnFull 在pool从还有空闲变成已满（所有的worker都在忙，并且开始拒绝任务）的时候调用，
+	// 变满之后要保持10ms才会调用，每次变满最多调用一次，只对Pool生效
+	OnFull func()
+
+	// OnSaturated 在执行任务的worker的数量达到容量（pool饱和）的时候调用，OnRecovered 在饱和之后第一个worker执行完任务的时候调用，
+	// 都只在状态变化的时候调用一次，在worker的goroutine中、pool.lock之外调用，只对Pool有效
+	OnSaturated func()
+	OnRecovered func()
+
+	// OverflowPool 不为nil的时候，Submit本来会因为pool已满返回ErrPoolOverload的任务会转交给它，
+	// 并返回它的提交结果，多个pool之间不能形成环，对Pool的各种提交方式都生效，SubmitTracked和SubmitNAtomic转交给备用pool的同名方法
+	OverflowPool *Pool
+
+	// AutoScale 不为nil的时候，pool会根据负载自动调整容量，扩缩容会通过Pool.Watch发出事件
+	AutoScale *AutoScale
+
+	// BaseContext 是pool的基础context，任务中可以通过TaskContext拿到它，之后可以通过Pool.SetBaseContext替换
+	BaseContext context.Context
+
+	// 当为true的时候，pool是同步的：Submit直接在调用者的goroutine中执行任务，不会创建任何goroutine，
+	// 容量固定为0，执行中的任务计入Running()，适合测试或者退化的配置
+	Synchronous bool
+
+	// HealthCheck等待探测任务完成的最长时间，为0的时候使用默认的1秒，只对Pool有效
+	ProbeTimeout time.Duration
+
+	// 所有worker的任务channel缓冲加起来最多占用的字节数，为0的时候不限制，
+	// 容量很大的pool会在创建时相应地减小每个worker的channel缓冲，容量不限制的pool忽略这个配置
+	MaxTaskBufferBytes int
+
+	// 每个提交来源的权重，pool繁忙的时候阻塞在SubmitFromSource上的各个来源按权重轮流拿到worker，
+	// 没有配置的来源权重为1，只对Pool有效
+	SourceWeights map[string]int
+
+	// pool已满的时候，Submit在阻塞等待之前最多自旋(让出执行权后重新检查)多少次，
+	// 任务很快就能执行完的时候可以避免阻塞和唤醒的开销，为0的时候不自旋，只对Pool有效
+	SpinWaitOnFull int
+
+	// 每个调用者阻塞在Submit上等待worker的最长时间，超时的时候返回ErrPoolOverload，为0的时候不限制
+	MaxBlockingDuration time.Duration
 }
 
 // WithOptions 入参是Options结构体
@@ -87,3 +233,277 @@ func WithLogger(logger Logger) Option {
 		opts.Logger = logger
 	}
 }
+
+// WithDisablePurge 设置是否关闭定期清理过期worker
+func WithDisablePurge(disable bool) Option {
+	return func(opts *Options) {
+		opts.DisablePurge = disable
+	}
+}
+
+// WithHotWorker 设置是否开启无锁获取最近归还的worker
+func WithHotWorker(hotWorker bool) Option {
+	return func(opts *Options) {
+		opts.HotWorker = hotWorker
+	}
+}
+
+// WithOnTaskDone 设置每个任务执行完之后的回调
+func WithOnTaskDone(onTaskDone func(dur time.Duration, recovered interface{})) Option {
+	return func(opts *Options) {
+		opts.OnTaskDone = onTaskDone
+	}
+}
+
+// WithOSThreads 设置每个worker独占一个OS线程
+func WithOSThreads() Option {
+	return func(opts *Options) {
+		opts.OSThreads = true
+	}
+}
+
+// WithCPUAffinity 设置worker绑定的CPU
+func WithCPUAffinity(cpus []int) Option {
+	return func(opts *Options) {
+		opts.CPUAffinity = cpus
+	}
+}
+
+// WithOnFull 设置pool变满时的回调
+func WithOnFull(onFull func()) Option {
+	return func(opts *Options) {
+		opts.OnFull = onFull
+	}
+}
+
+// WithOverflowPool 设置pool满了之后接收溢出任务的备用pool
+func WithOverflowPool(secondary *Pool) Option {
+	return func(opts *Options) {
+		opts.OverflowPool = secondary
+	}
+}
+
+// WithAutoScale 设置自动扩缩容，容量在[min, max]之间，阻塞的调用者不少于scaleUpThreshold时扩容，
+// 运行的goroutine持续少于min超过cooldown时缩容
+func WithAutoScale(min, max, scaleUpThreshold int, cooldown time.Duration) Option {
+	return func(opts *Options) {
+		opts.AutoScale = &AutoScale{
+			Min:              min,
+			Max:              max,
+			ScaleUpThreshold: scaleUpThreshold,
+			Cooldown:         cooldown,
+		}
+	}
+}
+
+// WithBaseContext 设置pool的基础context
+func WithBaseContext(ctx context.Context) Option {
+	return func(opts *Options) {
+		opts.BaseContext = ctx
+	}
+}
+
+// WithSynchronous 设置pool为同步执行任务
+func WithSynchronous() Option {
+	return func(opts *Options) {
+		opts.Synchronous = true
+	}
+}
+
+// WithProbeTimeout 设置HealthCheck的探测超时时间
+func WithProbeTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.ProbeTimeout = timeout
+	}
+}
+
+// WithMaxTaskBufferBytes 设置所有worker的任务channel缓冲的总字节数上限
+func WithMaxTaskBufferBytes(n int) Option {
+	return func(opts *Options) {
+		opts.MaxTaskBufferBytes = n
+	}
+}
+
+// WithSourceWeights 设置SubmitFromSource的各个来源的权重
+func WithSourceWeights(weights map[string]int) Option {
+	return func(opts *Options) {
+		opts.SourceWeights = weights
+	}
+}
+
+// WithOnSaturated 设置pool饱和时的回调
+func WithOnSaturated(onSaturated func()) Option {
+	return func(opts *Options) {
+		opts.OnSaturated = onSaturated
+	}
+}
+
+// WithOnRecovered 设置pool从饱和中恢复时的回调
+func WithOnRecovered(onRecovered func()) Option {
+	return func(opts *Options) {
+		opts.OnRecovered = onRecovered
+	}
+}
+
+// WithSpinWaitOnFull 设置pool已满的时候Submit自旋等待的次数
+func WithSpinWaitOnFull(iterations int) Option {
+	return func(opts *Options) {
+		opts.SpinWaitOnFull = iterations
+	}
+}
+
+// WithMaxBlockingDuration 设置阻塞在Submit上等待worker的最长时间
+func WithMaxBlockingDuration(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.MaxBlockingDuration = d
+	}
+}
+
+// WithPanicStormGuard 设置panic风暴的保护，window内的panic超过threshold次的时候暂时拒绝新的任务
+func WithPanicStormGuard(threshold int, window time.Duration) Option {
+	return func(opts *Options) {
+		opts.PanicStormThreshold = threshold
+		opts.PanicStormWindow = window
+	}
+}
+
+// WithLoadShedder 设置负载过高时丢弃任务的策略
+func WithLoadShedder(shedder LoadShedder) Option {
+	return func(opts *Options) {
+		opts.LoadShedder = shedder
+	}
+}
+
+// WithNoReuse 设置是否禁止复用worker
+func WithNoReuse(noReuse bool) Option {
+	return func(opts *Options) {
+		opts.NoReuse = noReuse
+	}
+}
+
+// WithMetricsSampler 设置定期采样Stats()的间隔和接收采样结果的sink
+func WithMetricsSampler(interval time.Duration, sink func(PoolStats)) Option {
+	return func(opts *Options) {
+		opts.MetricsInterval = interval
+		opts.MetricsSink = sink
+	}
+}
+
+// WithPreemptiveQueue 设置是否允许高优先级的提交挤掉等待中的低优先级提交
+func WithPreemptiveQueue(preemptive bool) Option {
+	return func(opts *Options) {
+		opts.PreemptiveQueue = preemptive
+	}
+}
+
+// WithCapacity 设置Pool.RebootWith重启之后的容量
+func WithCapacity(size int) Option {
+	return func(opts *Options) {
+		opts.Capacity = size
+	}
+}
+
+// WithLockProfiling 设置是否统计等待获取pool.lock的时间
+func WithLockProfiling(profiling bool) Option {
+	return func(opts *Options) {
+		opts.LockProfiling = profiling
+	}
+}
+
+// WithOnWorkerStart 设置worker goroutine启动时的回调
+func WithOnWorkerStart(onWorkerStart func(workerID uint64)) Option {
+	return func(opts *Options) {
+		opts.OnWorkerStart = onWorkerStart
+	}
+}
+
+// WithOnWorkerStop 设置worker goroutine退出时的回调
+func WithOnWorkerStop(onWorkerStop func(workerID uint64)) Option {
+	return func(opts *Options) {
+		opts.OnWorkerStop = onWorkerStop
+	}
+}
+
+// WithMaxTaskDuration 设置任务的最长执行时间，超过的时候放弃运行它的worker
+func WithMaxTaskDuration(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.MaxTaskDuration = d
+	}
+}
+
+// WithChanBufferSize 设置Pool.Chan返回的channel的缓冲大小
+func WithChanBufferSize(size int) Option {
+	return func(opts *Options) {
+		opts.ChanBufferSize = size
+	}
+}
+
+// WithTrackPendingTasks 设置是否记录还没有开始执行的任务
+func WithTrackPendingTasks(track bool) Option {
+	return func(opts *Options) {
+		opts.TrackPendingTasks = track
+	}
+}
+
+// WithTaskTracking 设置是否记录正在执行的任务，见DumpState
+func WithTaskTracking(tracking bool) Option {
+	return func(opts *Options) {
+		opts.TaskTracking = tracking
+	}
+}
+
+// WithCapHistory 设置记录最近多少次容量的变化
+func WithCapHistory(maxEntries int) Option {
+	return func(opts *Options) {
+		opts.CapHistory = maxEntries
+	}
+}
+
+// WithWarmWorkerCache 设置创建pool的时候预先放到workerCache中的goWorker的数量
+func WithWarmWorkerCache(n int) Option {
+	return func(opts *Options) {
+		opts.WarmWorkerCache = n
+	}
+}
+
+// WithMaxDelayedTasks 设置最多可以有多少个还没有到时间的延迟任务
+func WithMaxDelayedTasks(n int) Option {
+	return func(opts *Options) {
+		opts.MaxDelayedTasks = n
+	}
+}
+
+// WithWorkerLocalInit 设置创建worker本地对象的函数
+func WithWorkerLocalInit(init func() interface{}) Option {
+	return func(opts *Options) {
+		opts.WorkerLocalInit = init
+	}
+}
+
+// WithInitialWorkers 设置创建pool的时候预先启动的空闲worker的数量
+func WithInitialWorkers(n int) Option {
+	return func(opts *Options) {
+		opts.InitialWorkers = n
+	}
+}
+
+// WithSpawnRateLimit 设置每秒最多创建的worker goroutine的数量
+func WithSpawnRateLimit(perSecond int) Option {
+	return func(opts *Options) {
+		opts.SpawnRateLimit = perSecond
+	}
+}
+
+// WithMemoryBudget 设置通过SubmitWithMemEstimate提交的任务可以同时占用的内存（字节）
+func WithMemoryBudget(total int64) Option {
+	return func(opts *Options) {
+		opts.MemoryBudget = total
+	}
+}
+
+// WithRejectHandler 设置接收被抢占的任务的回调
+func WithRejectHandler(handler func(task func())) Option {
+	return func(opts *Options) {
+		opts.RejectHandler = handler
+	}
+}
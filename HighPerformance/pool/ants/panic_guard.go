@@ -0,0 +1,47 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// panicStormGuard 记录最近threshold+1次panic的时间，它们都落在window之内的时候触发保护，
+// 从最后一次panic开始的window之内tripped返回true
+type panicStormGuard struct {
+	window int64
+
+	lock  sync.Mutex
+	times []int64 // 环形缓冲，next指向最早的一次
+	next  int
+	count int
+
+	// until 在这个时间(nanotime)之前拒绝新的任务
+	until int64
+}
+
+func newPanicStormGuard(threshold int, window time.Duration) *panicStormGuard {
+	return &panicStormGuard{
+		window: int64(window),
+		times:  make([]int64, threshold+1),
+	}
+}
+
+// record 记录一次发生在now的panic
+func (g *panicStormGuard) record(now int64) {
+	g.lock.Lock()
+	g.times[g.next] = now
+	g.next = (g.next + 1) % len(g.times)
+	if g.count < len(g.times) {
+		g.count++
+	}
+	if g.count == len(g.times) && now-g.times[g.next] <= g.window {
+		atomic.StoreInt64(&g.until, now+g.window)
+	}
+	g.lock.Unlock()
+}
+
+// tripped 是否处于降级状态，g为nil的时候总是返回false
+func (g *panicStormGuard) tripped() bool {
+	return g != nil && nanotime() < atomic.LoadInt64(&g.until)
+}
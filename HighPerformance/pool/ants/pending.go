@@ -0,0 +1,67 @@
+package ants
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// pendingTask 是一个还没有开始执行的任务
+type pendingTask struct {
+	task       func()
+	enqueuedAt time.Time
+}
+
+// trackPending 是各种提交方式拿worker之前共用的一步：开启了TrackPendingTasks的时候记录task，
+// 返回交给worker执行的包装了exec的函数和提交失败时取消记录的函数，包装函数开始执行的时候取消记录。
+// exec是实际交给worker的函数，可能是task的包装，EachTask报告的是task的名字；没有开启的时候原样返回exec
+func (p *Pool) trackPending(task, exec func()) (run func(), untrack func()) {
+	if !p.options.TrackPendingTasks {
+		return exec, func() {}
+	}
+	p.pendingLock.Lock()
+	p.pendingSeq++
+	seq := p.pendingSeq
+	if p.pendingTasks == nil {
+		p.pendingTasks = make(map[uint64]pendingTask)
+	}
+	p.pendingTasks[seq] = pendingTask{task: task, enqueuedAt: time.Now()}
+	p.pendingLock.Unlock()
+
+	untrack = func() {
+		p.pendingLock.Lock()
+		delete(p.pendingTasks, seq)
+		p.pendingLock.Unlock()
+	}
+	run = func() {
+		untrack()
+		exec()
+	}
+	return
+}
+
+// EachTask 按提交的顺序对每个还没有开始执行的任务调用fn，funcName是任务函数的名字（通过runtime.FuncForPC获取，
+// 闭包的名字形如pkg.caller.func1），enqueuedAt是提交的时间。只有开启了TrackPendingTasks的时候才有记录，
+// 遍历的是调用时的快照，fn中可以安全地调用pool的方法
+func (p *Pool) EachTask(fn func(funcName string, enqueuedAt time.Time)) {
+	p.pendingLock.Lock()
+	seqs := make([]uint64, 0, len(p.pendingTasks))
+	for seq := range p.pendingTasks {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	tasks := make([]pendingTask, len(seqs))
+	for i, seq := range seqs {
+		tasks[i] = p.pendingTasks[seq]
+	}
+	p.pendingLock.Unlock()
+
+	for _, t := range tasks {
+		var name string
+		if f := runtime.FuncForPC(reflect.ValueOf(t.task).Pointer()); f != nil {
+			name = f.Name()
+		}
+		fn(name, t.enqueuedAt)
+	}
+}
@@ -0,0 +1,95 @@
+package ants
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pendingTestTask() {}
+
+func TestEachTask(t *testing.T) {
+	p, err := NewPool(1, WithTrackPendingTasks(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 任务交给一个不会运行的worker，在它的任务channel中等待
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.incRunning()
+	assert.True(t, p.revertWorker(w))
+	before := time.Now()
+	assert.NoError(t, p.Submit(pendingTestTask))
+
+	// 第二个任务阻塞在Submit上
+	go func() { _ = p.Submit(func() {}) }()
+	eventually(t, func() bool { return atomic.LoadInt32(&p.blockingNum) == 1 },
+		time.Second, 10*time.Millisecond)
+
+	var names []string
+	p.EachTask(func(funcName string, enqueuedAt time.Time) {
+		names = append(names, funcName)
+		assert.False(t, enqueuedAt.Before(before))
+	})
+	assert.Len(t, names, 2)
+	assert.True(t, strings.HasSuffix(names[0], ".pendingTestTask"), names[0])
+	assert.True(t, strings.Contains(names[1], ".TestEachTask."), names[1])
+
+	// 开始执行的任务不再出现
+	(<-w.task)()
+	var n int
+	p.EachTask(func(string, time.Time) { n++ })
+	assert.Equal(t, 1, n)
+
+	// 关闭之后阻塞的提交失败，不再是等待中的任务
+	p.Release()
+	p.decRunning()
+	p.lock.Lock()
+	p.cond.Broadcast()
+	p.lock.Unlock()
+	eventually(t, func() bool {
+		n = 0
+		p.EachTask(func(string, time.Time) { n++ })
+		return n == 0
+	}, time.Second, 10*time.Millisecond, "rejected task should not be pending")
+}
+
+func TestEachTaskAllSubmitPaths(t *testing.T) {
+	const n = 6
+	p, err := NewPool(n, WithTrackPendingTasks(true), WithPreemptiveQueue(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 每个任务都交给一个不会运行的worker，在它的任务channel中等待
+	workers := make([]*goWorker, n)
+	for i := range workers {
+		workers[i] = &goWorker{pool: p, task: make(chan func(), 1)}
+		p.incRunning()
+		defer p.decRunning()
+		assert.True(t, p.revertWorker(workers[i]))
+	}
+	_, err = p.SubmitTracked(pendingTestTask)
+	assert.NoError(t, err)
+	assert.NoError(t, p.SubmitBatch([]func(){pendingTestTask})[0])
+	assert.NoError(t, p.SubmitNAtomic([]func(){pendingTestTask}))
+	assert.NoError(t, p.SubmitWithPriority(1, pendingTestTask))
+	assert.NoError(t, p.SubmitFromSource("a", pendingTestTask))
+	assert.NoError(t, p.SubmitWithContext(context.Background(), pendingTestTask))
+
+	var names []string
+	p.EachTask(func(funcName string, _ time.Time) { names = append(names, funcName) })
+	assert.Len(t, names, n, "tasks from every submit path should be tracked")
+	for _, name := range names {
+		assert.True(t, strings.HasSuffix(name, ".pendingTestTask"), name)
+	}
+
+	for _, w := range workers {
+		(<-w.task)()
+	}
+	var left int
+	p.EachTask(func(string, time.Time) { left++ })
+	assert.Zero(t, left, "started tasks should no longer be pending")
+}
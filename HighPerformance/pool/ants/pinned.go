@@ -0,0 +1,80 @@
+package ants
+
+import "sync/atomic"
+
+// PinnedPool 返回key对应的只有一个worker的子pool，通过它提交的任务都交给同一个worker goroutine一个接一个地执行，
+// 适合需要按用户或者会话串行执行的任务。子pool使用p的配置（不包括AutoScale、OverflowPool、InitialWorkers和指标采样），
+// 它的worker过期退出之后，下一个任务会创建新的worker继续为这个key服务。
+// 子pool由p管理：没有worker也没有等待的提交、并且上一次清理之后没有再通过PinnedPool取过的时候，
+// 会在p的清理goroutine中被关闭并移除（关闭了定期清理的时候不会），
+// p关闭的时候也会全部关闭，
+// 所以不要长期持有返回的子pool，每次提交之前通过PinnedPool获取。p已经关闭的时候返回ErrPoolClosed
+func (p *Pool) PinnedPool(key string) (*Pool, error) {
+	p.pinnedLock.Lock()
+	defer p.pinnedLock.Unlock()
+	if p.IsClosed() {
+		return nil, ErrPoolClosed
+	}
+	// 调用者拿到子pool之后还没来得及提交，清理goroutine不能马上把它当作空闲的关闭
+	if p.pinnedFetched == nil {
+		p.pinnedFetched = make(map[string]struct{})
+	}
+	p.pinnedFetched[key] = struct{}{}
+	if pp, ok := p.pinned[key]; ok && !pp.IsClosed() {
+		return pp, nil
+	}
+	opts := *p.options
+	opts.Nonblocking = p.isNonblocking()
+	opts.AutoScale = nil
+	opts.OverflowPool = nil
+	opts.InitialWorkers = 0
+	opts.MetricsInterval, opts.MetricsSink = 0, nil
+	// 使用p当前的基础context，而不是创建p时的BaseContext
+	opts.BaseContext = p.baseContext()
+	pp, err := NewPool(1, WithOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	if p.pinned == nil {
+		p.pinned = make(map[string]*Pool)
+	}
+	p.pinned[key] = pp
+	return pp, nil
+}
+
+// purgePinned 关闭并移除空闲的子pool，在清理goroutine中调用。上一次清理之后通过PinnedPool取过的子pool留到下一次再检查
+func (p *Pool) purgePinned() {
+	p.pinnedLock.Lock()
+	defer p.pinnedLock.Unlock()
+	for key, pp := range p.pinned {
+		if _, fetched := p.pinnedFetched[key]; fetched {
+			continue
+		}
+		if pp.closeIfIdle() {
+			delete(p.pinned, key)
+		}
+	}
+	p.pinnedFetched = nil
+}
+
+// closeIfIdle 持有pool.lock再检查一次没有worker也没有等待的提交，是的话关闭pool并返回true
+func (p *Pool) closeIfIdle() bool {
+	p.lock.Lock()
+	idle := p.Running() == 0 && atomic.LoadInt32(&p.blockingNum) == 0 &&
+		atomic.CompareAndSwapInt32(&p.state, OPENED, CLOSED)
+	p.lock.Unlock()
+	if idle {
+		p.Release()
+	}
+	return idle
+}
+
+// releasePinned 关闭并移除所有的子pool，在p关闭的时候调用
+func (p *Pool) releasePinned() {
+	p.pinnedLock.Lock()
+	defer p.pinnedLock.Unlock()
+	for key, pp := range p.pinned {
+		pp.Release()
+		delete(p.pinned, key)
+	}
+}
@@ -0,0 +1,88 @@
+package ants
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinnedPool(t *testing.T) {
+	p, err := NewPool(10, WithExpiryDuration(20*time.Millisecond))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	a, err := p.PinnedPool("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, a.Cap(), "pinned pool should have a single worker")
+	a2, _ := p.PinnedPool("a")
+	assert.True(t, a == a2, "the same key should return the same pinned pool")
+	b, _ := p.PinnedPool("b")
+	assert.False(t, a == b)
+
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, a.Submit(func() {
+		defer wg.Done()
+		<-block
+	}))
+
+	// 空闲的子pool会被清理，正在运行任务的保留
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, b.IsClosed(), "idle pinned pool should be cleaned up")
+	assert.False(t, a.IsClosed(), "busy pinned pool should be kept")
+	b2, _ := p.PinnedPool("b")
+	assert.False(t, b == b2 || b2.IsClosed(), "a new pinned pool should be created after cleanup")
+	close(block)
+	wg.Wait()
+
+	p.Release()
+	assert.True(t, a.IsClosed(), "pinned pools should be released with the parent")
+	assert.True(t, b2.IsClosed())
+	_, err = p.PinnedPool("a")
+	assert.Equal(t, ErrPoolClosed, err)
+}
+
+func TestPinnedPoolBaseContext(t *testing.T) {
+	p, err := NewPool(10, WithBaseContext(context.WithValue(context.Background(), traceKey{}, "span-1")))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	traceOf := func(sub *Pool) interface{} {
+		return sub.baseContext().Value(traceKey{})
+	}
+
+	a, err := p.PinnedPool("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "span-1", traceOf(a))
+
+	// 之后创建的子pool使用当前的基础context，已经存在的子pool也一起替换
+	p.SetBaseContext(context.WithValue(context.Background(), traceKey{}, "span-2"))
+	b, err := p.PinnedPool("b")
+	assert.NoError(t, err)
+	assert.Equal(t, "span-2", traceOf(b))
+	assert.Equal(t, "span-2", traceOf(a))
+}
+
+func TestPinnedPoolFetchedNotPurged(t *testing.T) {
+	p, err := NewPool(10, WithDisablePurge(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	a, err := p.PinnedPool("a")
+	assert.NoError(t, err)
+	// 刚取出的子pool还没有提交任务，这一次清理不能关闭它
+	p.purgePinned()
+	assert.False(t, a.IsClosed(), "a just fetched pinned pool should survive the next purge")
+	assert.NoError(t, a.Submit(demoFunc))
+
+	// 之后一直没有再取过，空闲的时候被清理
+	b, _ := p.PinnedPool("b")
+	p.purgePinned()
+	p.purgePinned()
+	assert.True(t, b.IsClosed(), "idle pinned pool should be cleaned up")
+	assert.Equal(t, ErrPoolClosed, b.Submit(demoFunc))
+}
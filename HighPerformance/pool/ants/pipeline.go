@@ -0,0 +1,59 @@
+package ants
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// SubmitPipeline 把stages串成一条单元素的流水线：stages[0](input)的输出交给stages[1]，依此类推，
+// 每个阶段作为单独的任务提交，并且只在上一个阶段完成之后才提交。返回的句柄的结果是最后一个阶段的输出，
+// stages为空的时候直接以input作为结果。
+// 任意阶段panic或者提交失败的时候流水线停止，Await返回对应的错误，panic的值可以用errors.As取出*PanicError；
+// Cancel只能在第一个阶段开始之前取消整条流水线
+func (p *Pool) SubmitPipeline(stages []func(interface{}) interface{}, input interface{}) *Task {
+	t := &Task{done: make(chan struct{})}
+	if len(stages) == 0 {
+		t.state = taskFinished
+		t.finish(input, nil)
+		return t
+	}
+	err := p.Submit(func() {
+		if !atomic.CompareAndSwapInt32(&t.state, taskPending, taskRunning) {
+			return
+		}
+		t.runStage(p, stages, 0, input)
+	})
+	if err != nil && atomic.CompareAndSwapInt32(&t.state, taskPending, taskFinished) {
+		t.finish(nil, err)
+	}
+	return t
+}
+
+// runStage 在worker中执行第i个阶段，完成之后提交下一个阶段或者结束整条流水线
+func (t *Task) runStage(p *Pool, stages []func(interface{}) interface{}, i int, v interface{}) {
+	out, err := runPipelineStage(p, stages[i], i, v)
+	if err != nil || i == len(stages)-1 {
+		atomic.StoreInt32(&t.state, taskFinished)
+		t.finish(out, err)
+		return
+	}
+	// 下一个阶段同样通过p.Submit交给pool的worker执行，受pool容量的限制；只是等待worker的这一步放到新的goroutine里，
+	// 当前阶段所在的worker可以先回到pool。在当前worker里阻塞等待的话，它自己占着一个名额，容量为1的pool会死锁
+	go func() {
+		if err := p.Submit(func() { t.runStage(p, stages, i+1, out) }); err != nil {
+			atomic.StoreInt32(&t.state, taskFinished)
+			t.finish(nil, err)
+		}
+	}()
+}
+
+// runPipelineStage 执行一个阶段，把panic转换成包装了*PanicError的错误，错误信息中带有阶段的序号
+func runPipelineStage(p *Pool, stage func(interface{}) interface{}, i int, v interface{}) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.recordPanic(r)
+			out, err = nil, fmt.Errorf("pipeline stage %d: %w", i, &PanicError{Value: r})
+		}
+	}()
+	return stage(v), nil
+}
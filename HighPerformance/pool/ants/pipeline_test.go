@@ -0,0 +1,55 @@
+package ants
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitPipeline(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	var stage int32
+	stages := []func(interface{}) interface{}{
+		func(v interface{}) interface{} {
+			assert.True(t, atomic.CompareAndSwapInt32(&stage, 0, 1))
+			return v.(int) + 1
+		},
+		func(v interface{}) interface{} {
+			assert.True(t, atomic.CompareAndSwapInt32(&stage, 1, 2), "stage 1 must start after stage 0 completes")
+			return v.(int) * 10
+		},
+		func(v interface{}) interface{} {
+			assert.True(t, atomic.CompareAndSwapInt32(&stage, 2, 3), "stage 2 must start after stage 1 completes")
+			return v.(int) - 3
+		},
+	}
+	v, err := p.SubmitPipeline(stages, 1).Await(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 17, v)
+
+	v, err = p.SubmitPipeline(nil, "input").Await(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "input", v)
+
+	var ran int32
+	panicking := []func(interface{}) interface{}{
+		func(v interface{}) interface{} { panic("boom") },
+		func(v interface{}) interface{} { atomic.AddInt32(&ran, 1); return v },
+	}
+	_, err = p.SubmitPipeline(panicking, 0).Await(context.Background())
+	assert.EqualError(t, err, "pipeline stage 0: task panicked: boom")
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe), "the panic value should be reachable through errors.As")
+	assert.Equal(t, "boom", pe.Value)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&ran), "stages after a panic should not run")
+
+	p.Release()
+	_, err = p.SubmitPipeline(stages, 1).Await(context.Background())
+	assert.Equal(t, ErrPoolClosed, err)
+}
@@ -23,10 +23,14 @@
 package ants
 
 import (
+	"context"
 	"github.com/panjf2000/ants/v2/internal"
+	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
 // Pool 接收来自client的任务, 它用过一个给定的大小，限制总共的goroutine的数量，以实现循环使用goroutine
@@ -38,12 +42,22 @@ type Pool struct {
 	// running 当前运行的goroutine的数量
 	running int32
 
+	// alive 还没有完全退出的worker goroutine的数量，worker在running减1之后还要做一些清理工作，这之后alive才减1
+	alive int32
+
+	// purging 还没有退出的清理goroutine的数量，在启动goroutine之前加1，goroutine退出的最后一步减1
+	purging int32
+
 	// workers 是一个用来存储可用的worker的切片
-	workers workerArray
+	workers WorkerArray
 
 	// state 用来提示pool，它自己已经关闭
 	state int32
 
+	// epoch 每次Reboot成功的时候加1，清理goroutine发现epoch和启动它时的不一样的时候就退出，
+	// 避免Release之后马上Reboot的时候旧的清理goroutine没有发现pool关闭过而一直运行下去
+	epoch uint32
+
 	// lock 用来保证同步操作
 	lock sync.Locker
 
@@ -53,29 +67,212 @@ type Pool struct {
 	// workerCache 加速获取一个可用的worker，
 	workerCache sync.Pool
 
-	// blockingNum 是已经在pool.Submit处被阻塞的goroutine的数量, 被pool.lock保护
-	blockingNum int
+	// nonblocking 运行时是否非阻塞，创建的时候取Options.Nonblocking，之后可以通过SetNonblocking修改，通过原子操作存取
+	nonblocking int32
+
+	// cancelGen 每次CancelPending的时候加1，pendingWaiters 是在retrieveWorker中等待p.cond的调用者的数量，都在pool.lock内读写
+	cancelGen      uint32
+	pendingWaiters int
+
+	// blockingNum 是已经在pool.Submit处被阻塞的goroutine的数量, 在pool.lock内修改，开启HotWorker的时候会在锁外读取
+	blockingNum int32
+
+	// hotWorker 开启HotWorker的时候存放最近归还的一个worker(*goWorker)，通过原子操作存取
+	hotWorker unsafe.Pointer
 
 	//pool的配置：过期清理时间、是否需要预先分配内存、处理panic的处理器等
 	options *Options
+
+	// lastPurgeTime 清理goroutine最近一次运行的时间(UnixNano)，用来观察清理goroutine是否还活着
+	lastPurgeTime int64
+
+	// busy 正在执行任务的worker的数量，saturated 为1代表所有的worker都在执行任务，用来触发OnSaturated和OnRecovered
+	busy      int32
+	saturated int32
+
+	// full 为1代表pool已满，被拒绝过任务并且之后还没有worker空闲下来，fullGen每次变成已满的时候加1，用来给OnFull去抖
+	full    int32
+	fullGen uint32
+
+	// panicCount 任务发生panic的次数，lastPanic 最近一次panic的记录(*panicRecord)
+	panicCount uint64
+	lastPanic  atomic.Value
+
+	// workerSeq 用来给worker分配id
+	workerSeq uint64
+
+	// droppedResults SubmitInto丢弃的结果的数量
+	droppedResults uint64
+
+	// blockingTimeouts 阻塞等待超过MaxBlockingDuration的次数
+	blockingTimeouts uint64
+
+	// heartbeatLock 保护heartbeatStop，heartbeatStop 关闭的时候当前的Heartbeat goroutine退出
+	heartbeatLock sync.Mutex
+	heartbeatStop chan struct{}
+
+	// watchLock 保护watchers、watchInterval和watchStop，watchers 是通过WatchMetrics注册的接收者，
+	// watchInterval 是推送指标的goroutine当前的间隔，watchStop 在它运行的时候不为nil，关闭的时候它退出
+	watchLock     sync.Mutex
+	watchers      map[<-chan PoolMetrics]*metricsWatcher
+	watchInterval time.Duration
+	watchStop     chan struct{}
+
+	// chanLock 保护taskChan，taskChan 是Chan返回的channel，CloseChan或者pool关闭之后重新置为nil
+	chanLock sync.Mutex
+	taskChan chan func()
+
+	// pendingLock 保护pendingTasks，pendingTasks 是开启TrackPendingTasks的时候还没有开始执行的任务，key为pendingSeq分配的序号
+	pendingLock  sync.Mutex
+	pendingTasks map[uint64]pendingTask
+	pendingSeq   uint64
+
+	// pinnedLock 保护pinned和pinnedFetched，pinned 是PinnedPool创建的子pool，key为PinnedPool的参数，
+	// pinnedFetched 是上一次清理之后通过PinnedPool取过的key，这些子pool这一次清理的时候不会被关闭
+	pinnedLock    sync.Mutex
+	pinned        map[string]*Pool
+	pinnedFetched map[string]struct{}
+
+	// delayLock 保护delayed、delayTimer和delaySeq，delayed 是SubmitAfterMany提交的还没有到时间的任务，按到期时间排序，
+	// delayTimer 在最早的任务到期的时候触发，delaySeq 让到期时间相同的任务按提交的顺序执行
+	delayLock  sync.Mutex
+	delayed    delayHeap
+	delayTimer *time.Timer
+	delaySeq   uint64
+
+	// droppedTasks 被LoadShedder丢弃的任务的数量
+	droppedTasks uint64
+
+	// expiredTasks 超过DeadlineAfter还没有开始执行而被跳过的TaskEnvelope，以及ctx已经取消而被跳过的SubmitWithContext任务的数量
+	expiredTasks uint64
+
+	// abandonedWorkers 因为任务超过MaxTaskDuration而被放弃的worker的数量
+	abandonedWorkers uint64
+
+	// inFlight 已经交给worker但还没有执行完的任务的数量，见InFlight
+	inFlight int32
+
+	// stormGuard 设置了PanicStormGuard的时候记录最近的panic
+	stormGuard *panicStormGuard
+
+	// runningLock 保护runningTasks，runningTasks 是开启TaskTracking的时候正在执行的任务，key为worker的id
+	runningLock  sync.Mutex
+	runningTasks map[uint64]runningTask
+
+	// capacityCond 等待可用的worker变多，capacityWaiters 是阻塞在WaitForCapacity中的调用者的数量，见WaitForCapacity
+	capacityCond    *sync.Cond
+	capacityWaiters int32
+
+	// capFactor NewPoolProportional创建的pool的容量和GOMAXPROCS的比例，Reboot的时候用来重新计算容量，其他的pool为0
+	capFactor float64
+
+	// capHistory 开启CapHistory的时候最近的容量变化
+	capHistory *capHistory
+
+	// completions 最近完成的任务的时间，见ThroughputLast
+	completions completionLog
+
+	// memLock 保护memInUse，memCond 等待内存预算，和pool.lock分开，避免归还内存的唤醒被等待worker的提交者拿走
+	memLock  sync.Mutex
+	memCond  *sync.Cond
+	memInUse int64
+
+	// spawnLimiter 设置了SpawnRateLimit的时候限制创建worker的速率
+	spawnLimiter *spawnLimiter
+
+	// cpuCursor 开启CPUAffinity的时候，用来轮流给worker分配CPU
+	cpuCursor uint32
+
+	// events 用来发出pool的事件
+	events chan Event
+
+	// lowSince 开启AutoScale的时候，Running()开始低于下限的时间，只在清理goroutine中访问
+	lowSince time.Time
+
+	// baseCtx 存放基础context(contextHolder)，每个任务开始执行的时候注入到worker中
+	baseCtx atomic.Value
+
+	// shutdown 存放当前的关闭信号(shutdownHolder)，Release的时候取消，Reboot的时候换成新的
+	shutdown atomic.Value
+
+	// waiters 开启PreemptiveQueue的时候阻塞在SubmitWithPriority上的提交，被pool.lock保护
+	waiters []*priorityWaiter
+
+	// sources 决定阻塞在SubmitFromSource上的哪个来源先拿到worker，被pool.lock保护
+	sources *sourceScheduler
+
+	// taskChanCap 每个worker的任务channel的缓冲大小，创建pool的时候根据MaxTaskBufferBytes确定
+	taskChanCap int
 }
 
-// purgePeriodically 定期清除过期的workers，它会单独运行一个goroutine作为清理者
-func (p *Pool) purgePeriodically() {
+// purgeHook 保存一个func(*Pool)，清理goroutine每一轮取出过期的worker之前调用，测试的时候可以替换掉。
+// 默认pool的清理goroutine一直在运行，所以用atomic.Value保存
+var purgeHook atomic.Value
+
+// startPurge 启动清理goroutine
+func (p *Pool) startPurge(epoch uint32) {
+	atomic.AddInt32(&p.purging, 1)
+	go p.purgePeriodically(epoch)
+}
+
+// purgePeriodically 定期清除过期的workers，它会单独运行一个goroutine作为清理者，通过startPurge启动
+func (p *Pool) purgePeriodically(epoch uint32) {
+	defer atomic.AddInt32(&p.purging, -1)
+
 	// 定期
 	heartbeat := time.NewTicker(p.options.ExpiryDuration)
 	defer heartbeat.Stop()
 
-	for range heartbeat.C {
-		//pool是否已经关闭
-		if p.IsClosed() {
+	// 清理goroutine发生panic的时候记录日志并重新启动，避免过期的worker再也得不到回收
+	defer func() {
+		if r := recover(); r != nil {
+			p.options.Logger.Printf("purge goroutine exits from a panic: %v, restarting\n", r)
+			if !p.IsClosed() && atomic.LoadUint32(&p.epoch) == epoch {
+				p.startPurge(epoch)
+			}
+		}
+	}()
+
+	// pool关闭的时候马上退出，不用等到下一次运行
+	shutdown := p.ShutdownContext().Done()
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-heartbeat.C:
+		}
+		//pool是否已经关闭，或者已经关闭之后又重启了
+		if p.IsClosed() || atomic.LoadUint32(&p.epoch) != epoch {
 			break
 		}
+		atomic.StoreInt64(&p.lastPurgeTime, time.Now().UnixNano())
+		p.autoScale()
+		p.purgePinned()
+		// 在取出过期的worker之前调用，发生panic的时候不会有取出来还没通知退出的worker
+		if hook, _ := purgeHook.Load().(func(*Pool)); hook != nil {
+			hook(p)
+		}
 
-		p.lock.Lock()
 		//过期的workers
-		expiredWorkers := p.workers.retrieveExpiry(p.options.ExpiryDuration)
-		p.lock.Unlock()
+		var expiredWorkers []*goWorker
+		// 还有调用者阻塞在Submit上的时候，空闲的worker马上就会被用到，这一轮跳过清理，避免清理之后又要重新创建goroutine；
+		// 这时也不获取pool.lock，ExpiryDuration很小的时候避免频繁地和等待中的调用者争抢锁
+		if atomic.LoadInt32(&p.blockingNum) == 0 {
+			p.lock.Lock()
+			expiredWorkers = p.workers.RetrieveExpiry(p.options.ExpiryDuration)
+			// hotWorker也可能过期，先把它取出来再判断，没过期的话放回去
+			if w := p.takeHotWorker(); w != nil {
+				if time.Duration(nanotime()-w.recycleTime) > p.options.ExpiryDuration {
+					expiredWorkers = append(expiredWorkers, w)
+				} else if !atomic.CompareAndSwapPointer(&p.hotWorker, nil, unsafe.Pointer(w)) {
+					// 槽位已经被新归还的worker占了
+					if err := p.workers.Insert(w); err != nil {
+						expiredWorkers = append(expiredWorkers, w)
+					}
+				}
+			}
+			p.lock.Unlock()
+		}
 
 		// Notify obsolete workers to stop.提醒过期的worker停止
 		// This notification must be outside the p.lock, since w.task may be blocking and may consume a lot of time if many workers
@@ -91,9 +288,11 @@ func (p *Pool) purgePeriodically() {
 		// while some invokers still get stuck in "p.cond.Wait()",
 		// then it ought to wakes all those invokers.
 		//可能存在所有worker都被清理过的情况（没有任何worker在运行） 尽管某些调用程序仍然卡在“ p.cond.Wait（）”中， 那么它应该唤醒所有这些调用者。
-		if p.Running() == 0 {
-			//唤醒所有的等待获取worker的goroutine
-			p.cond.Broadcast()
+		if p.Running() == 0 && atomic.LoadInt32(&p.blockingNum) > 0 {
+			//唤醒等待获取worker的goroutine，只唤醒能创建出worker的数量，不限制容量的时候全部唤醒
+			p.lock.Lock()
+			p.wakeWaiters(p.Free())
+			p.lock.Unlock()
 		}
 	}
 }
@@ -101,95 +300,619 @@ func (p *Pool) purgePeriodically() {
 // NewPool 创建一个pool实例
 func NewPool(size int, options ...Option) (*Pool, error) {
 	opts := loadOptions(options...)
-	// 没有限制的pool
-	if size <= 0 {
-		size = -1
-	}
-
-	if expiry := opts.ExpiryDuration; expiry < 0 {
-		return nil, ErrInvalidPoolExpiry
-	} else if expiry == 0 {
-		// 使用默认的过期时间间隔
-		opts.ExpiryDuration = DefaultCleanIntervalTime
-	}
-	// 使用默认的日志组件
-	if opts.Logger == nil {
-		opts.Logger = defaultLogger
+	size, err := prepareOptions(size, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	p := &Pool{
 		capacity: int32(size),
 		lock:     internal.NewSpinLock(), //锁
 		options:  opts,
+		events:   make(chan Event, eventChanCap),
+	}
+	if opts.LockProfiling {
+		p.lock = &profiledLock{Locker: p.lock}
+	}
+	p.sources = newSourceScheduler(opts.SourceWeights)
+	if opts.PanicStormThreshold > 0 && opts.PanicStormWindow > 0 {
+		p.stormGuard = newPanicStormGuard(opts.PanicStormThreshold, opts.PanicStormWindow)
 	}
+	p.spawnLimiter = newSpawnLimiter(opts.SpawnRateLimit)
+	p.setNonblocking(opts.Nonblocking)
+	p.capHistory = newCapHistory(opts.CapHistory)
+	p.taskChanCap = taskChanCap(size, opts.MaxTaskBufferBytes, unsafe.Sizeof(func() {}))
 	// sync.pool：当调用sync.Pool的get方法时，如果没有更多的空闲元素，就会调用这个New方法来创建一个
 	// 如果没有New方法时就会返回nil
 	p.workerCache.New = func() interface{} {
 		return &goWorker{
 			pool: p,                                //当前worker所属的pool
-			task: make(chan func(), workerChanCap), //任务的大小
+			task: make(chan func(), p.taskChanCap), //任务的大小
 		}
 	}
+	if p.options.BaseContext != nil {
+		p.SetBaseContext(p.options.BaseContext)
+	}
+	p.resetShutdown()
 	// 预先分配内存
 	if p.options.PreAlloc {
-		if size == -1 {
-			return nil, ErrInvalidPreAllocSize
-		}
-		p.workers = newWorkerArray(loopQueueType, size)
+		p.workers = NewWorkerArray(LoopQueueType, size)
 	} else {
-		p.workers = newWorkerArray(stackType, 0)
+		p.workers = NewWorkerArray(StackType, 0)
 	}
 
 	// 等待
 	p.cond = sync.NewCond(p.lock)
+	p.memCond = sync.NewCond(&p.memLock)
+	p.warmWorkerCache()
+	p.capacityCond = sync.NewCond(p.lock)
+
+	p.spawnInitialWorkers()
 
 	// 使用一个goroutine来清理过期的workers
-	go p.purgePeriodically()
+	if !p.options.DisablePurge {
+		atomic.StoreInt64(&p.lastPurgeTime, time.Now().UnixNano())
+		p.startPurge(0)
+	}
+	p.startMetricsSampler()
 
 	return p, nil
 }
 
+// Clone 用和p相同的配置和当前的容量创建一个新的pool，比如给每个分片创建一个配置相同的pool，
+// 新的pool没有任何worker，计数也都从零开始，p已经关闭也可以克隆。配置是浅拷贝的，
+// Logger、各种回调、LoadShedder、OverflowPool、BaseContext等引用类型的配置和p共享
+func (p *Pool) Clone() (*Pool, error) {
+	opts := *p.options
+	opts.Nonblocking = p.isNonblocking()
+	return NewPool(p.Cap(), WithOptions(opts))
+}
+
+// prepareOptions 检查opts并填上默认值，返回规整之后的容量，NewPool和RebootWith共用
+func prepareOptions(size int, opts *Options) (int, error) {
+	// 没有限制的pool
+	if size <= 0 {
+		size = -1
+	}
+	// 同步的pool在调用者的goroutine中执行任务，不需要worker，也就不需要清理
+	if opts.Synchronous {
+		size = 0
+		opts.PreAlloc = false
+		opts.DisablePurge = true
+	}
+
+	if expiry := opts.ExpiryDuration; expiry < 0 {
+		return 0, ErrInvalidPoolExpiry
+	} else if expiry == 0 {
+		// 使用默认的过期时间间隔
+		opts.ExpiryDuration = DefaultCleanIntervalTime
+	}
+	// 使用默认的日志组件
+	if opts.Logger == nil {
+		opts.Logger = defaultLogger
+	}
+	// 每个worker独占一个OS线程，容量不限制的时候线程数也没有上限
+	if opts.OSThreads && size == -1 {
+		opts.Logger.Printf("ants: WithOSThreads on a pool with unlimited capacity may lock an unbounded number of OS threads\n")
+	}
+	if opts.AutoScale != nil {
+		if err := opts.AutoScale.validate(); err != nil {
+			return 0, err
+		}
+	}
+	if opts.PreAlloc && size == -1 {
+		return 0, ErrInvalidPreAllocSize
+	}
+	if opts.InitialWorkers < 0 || opts.SpawnRateLimit < 0 || opts.MemoryBudget < 0 || opts.CapHistory < 0 || opts.WarmWorkerCache < 0 ||
+		opts.MaxDelayedTasks < 0 {
+		return 0, ErrInvalidPoolConfig
+	}
+	if opts.Synchronous {
+		opts.InitialWorkers = 0
+	} else if size != -1 && opts.InitialWorkers > size {
+		return 0, ErrSpawnExceedsCap
+	}
+	return size, nil
+}
+
 // ---------------------------------------------------------------------------
 
 // Submit 提交一个任务到pool中
 func (p *Pool) Submit(task func()) error {
+	return p.submit(context.Background(), task)
+}
+
+// submit 是Submit、SubmitOrBlock和SubmitTimeout的实现，ctx结束的时候放弃等待worker并返回ErrContextCancelled，
+// ctx同时用来给retrieveWorkerOrCancel传递提交的标记
+func (p *Pool) submit(ctx context.Context, task func()) error {
+	if dropped, err := p.admit(); dropped || err != nil {
+		return err
+	}
+	if p.options.Synchronous {
+		p.runInline(task)
+		return nil
+	}
+	// run 是交给worker执行的函数，记录等待中的任务的时候包装了task
+	run, untrack := p.trackPending(task, task)
+	// 获得一个可用的worker来运行任务
+	stop := p.wakeOnDone(ctx)
+	w, cancelled := p.retrieveWorkerOrCancel(ctx)
+	stop()
+	if w == nil {
+		untrack()
+		switch {
+		case cancelled:
+			return ErrTaskCancelled
+		case ctx.Err() != nil:
+			return ErrContextCancelled
+		case p.IsClosed():
+			// 等待的过程中pool被关闭了，不转交给备用的pool
+			return ErrPoolClosed
+		}
+		return p.overflow(task)
+	}
+	// add task
+	w.dispatch(run)
+	return nil
+}
+
+// admit 是所有提交方式共用的入口检查：pool已经关闭或者被PanicStormGuard拒绝的时候返回对应的错误，
+// 被LoadShedder丢弃的时候返回dropped为true，调用者把这次提交当作成功直接返回
+func (p *Pool) admit() (dropped bool, err error) {
+	if p.IsClosed() {
+		return false, ErrPoolClosed
+	}
+	if p.stormGuard.tripped() {
+		return false, ErrPanicStorm
+	}
+	return p.shed(), nil
+}
+
+// overflow 在拿不到worker的时候调用，设置了OverflowPool的时候把task转交给它，否则返回ErrPoolOverload
+func (p *Pool) overflow(task func()) error {
+	if secondary := p.options.OverflowPool; secondary != nil {
+		return secondary.Submit(task)
+	}
+	return ErrPoolOverload
+}
+
+// TrySubmitFast 提交一个任务，不管有没有设置Nonblocking都不会阻塞，提交成功返回true；
+// pool已满、已经关闭、被PanicStormGuard拒绝或者被LoadShedder丢弃的时候返回false，不构造错误，适合不关心拒绝原因的热点循环
+func (p *Pool) TrySubmitFast(task func()) bool {
+	if dropped, err := p.admit(); dropped || err != nil {
+		return false
+	}
+	if p.options.Synchronous {
+		p.runInline(task)
+		return true
+	}
+	w := p.tryRetrieveWorker()
+	if w == nil {
+		if secondary := p.options.OverflowPool; secondary != nil {
+			return secondary.TrySubmitFast(task)
+		}
+		return false
+	}
+	w.dispatch(task)
+	return true
+}
+
+// SubmitIfNotFull 在Free()大于threshold的时候才提交任务，否则立刻返回ErrPoolOverload，
+// 适合把资源让给前台任务的低优先级后台任务。检查和提交之间不加锁，并发提交的时候是一个软限制
+func (p *Pool) SubmitIfNotFull(threshold int, task func()) error {
 	if p.IsClosed() {
 		return ErrPoolClosed
 	}
-	var w *goWorker
-	// 获得一个可用的worker来运行任务
-	if w = p.retrieveWorker(); w == nil {
+	if p.Free() <= threshold {
 		return ErrPoolOverload
 	}
-	// add task
-	w.task <- task
+	return p.Submit(task)
+}
+
+// SubmitTimeout 提交一个任务，最多等待d拿到worker，超时返回ErrSubmitTimeout。限制的是等待worker的时间，不是任务执行的时间，
+// 和对所有提交都生效的MaxBlockingDuration不同，只对这一次提交生效；非阻塞的pool没有空闲worker的时候和Submit一样返回ErrPoolOverload
+func (p *Pool) SubmitTimeout(d time.Duration, task func()) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	if err := p.submit(ctx, task); err != ErrContextCancelled {
+		return err
+	}
+	return ErrSubmitTimeout
+}
+
+// WithWorker 在pool的一个worker上执行fn并等待它结束，用来限制一段同步代码的并发数：拿不到worker的时候和Submit一样阻塞或者返回错误。
+// fn中的panic会被recover并以*PanicError返回，计入Panics()但不交给PanicHandler，worker照常回到pool，不会少一个名额
+func (p *Pool) WithWorker(fn func()) error {
+	done := make(chan error, 1)
+	err := p.Submit(func() {
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				p.recordPanic(r)
+				err = &PanicError{Value: r}
+			}
+			done <- err
+		}()
+		fn()
+	})
+	if err != nil {
+		return err
+	}
+	return <-done
+}
+
+// SubmitWithPanicHandler 提交一个任务，任务发生panic的时候调用handler而不是Options中的PanicHandler，
+// panic仍然会计入Panics()；handler为nil的时候和Submit一样
+func (p *Pool) SubmitWithPanicHandler(task func(), handler func(interface{})) error {
+	if handler == nil {
+		return p.Submit(task)
+	}
+	return p.Submit(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				p.recordPanic(r)
+				handler(r)
+			}
+		}()
+		task()
+	})
+}
+
+// SubmitWithCallback 提交一个任务，任务结束后在worker的goroutine中调用done，发生panic的时候recovered是panic的值，否则为nil，
+// 发生的panic由done处理，不会再交给PanicHandler，但仍然会计入Panics()；done为nil的时候和Submit一样
+func (p *Pool) SubmitWithCallback(task func(), done func(recovered interface{})) error {
+	if done == nil {
+		return p.Submit(task)
+	}
+	return p.Submit(func() {
+		defer func() {
+			r := recover()
+			if r != nil {
+				p.recordPanic(r)
+			}
+			done(r)
+		}()
+		task()
+	})
+}
+
+// SubmitTracked 提交一个任务，并返回运行这个任务的worker的id，同一个goroutine被复用的时候id不变，
+// 可以用来在测试和日志中关联任务和运行它的goroutine
+func (p *Pool) SubmitTracked(task func()) (workerID uint64, err error) {
+	if dropped, err := p.admit(); dropped || err != nil {
+		return 0, err
+	}
+	// 同步的pool没有worker
+	if p.options.Synchronous {
+		p.runInline(task)
+		return 0, nil
+	}
+	run, untrack := p.trackPending(task, task)
+	w, cancelled := p.retrieveWorkerOrCancel(context.Background())
+	if w == nil {
+		untrack()
+		if cancelled {
+			return 0, ErrTaskCancelled
+		}
+		if secondary := p.options.OverflowPool; secondary != nil {
+			return secondary.SubmitTracked(task)
+		}
+		return 0, ErrPoolOverload
+	}
+	workerID = w.id
+	w.dispatch(run)
+	return workerID, nil
+}
+
+// SubmitBatch 批量提交任务，返回的切片和tasks一一对应，提交成功的位置为nil
+func (p *Pool) SubmitBatch(tasks []func()) []error {
+	return p.SubmitBatchCtx(context.Background(), tasks)
+}
+
+// SubmitBatchCtx 批量提交任务，ctx被取消后立刻停止提交（包括正阻塞在等待worker上的提交），
+// 从中断的位置开始之后所有的位置都为ErrContextCancelled，调用者可以据此知道有多少个任务已经提交成功
+func (p *Pool) SubmitBatchCtx(ctx context.Context, tasks []func()) []error {
+	errs := make([]error, len(tasks))
+
+	// ctx被取消的时候唤醒阻塞在retrieveWorker()中的提交
+	defer p.wakeOnDone(ctx)()
+
+	for i, task := range tasks {
+		dropped, err := p.admit()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if dropped {
+			continue
+		}
+		if p.options.Synchronous && ctx.Err() == nil {
+			p.runInline(task)
+			continue
+		}
+		var (
+			w         *goWorker
+			cancelled bool
+		)
+		run, untrack := p.trackPending(task, task)
+		if ctx.Err() == nil {
+			w, cancelled = p.retrieveWorkerOrCancel(ctx)
+		}
+		if w == nil {
+			untrack()
+			if cancelled {
+				errs[i] = ErrTaskCancelled
+				continue
+			}
+			if ctx.Err() == nil {
+				errs[i] = p.overflow(task)
+				continue
+			}
+			for j := i; j < len(tasks); j++ {
+				errs[j] = ErrContextCancelled
+			}
+			break
+		}
+		w.dispatch(run)
+	}
+	return errs
+}
+
+// SubmitNAtomic 提交一组任务，要么全部提交，要么一个都不提交：空闲的worker加上还能创建的worker不够len(tasks)个的时候
+// 返回ErrInsufficientCapacity，不会阻塞等待。检查和取出worker在同一次持有pool.lock的时候完成，其他提交者没法在中间拿走worker。
+// 设置了SpawnRateLimit并且需要创建的worker超出了速率的时候同样返回ErrInsufficientCapacity
+func (p *Pool) SubmitNAtomic(tasks []func()) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	if dropped, err := p.admit(); err != nil {
+		return err
+	} else if dropped {
+		// 整组任务一起被丢弃，admit只计了一个
+		atomic.AddUint64(&p.droppedTasks, uint64(len(tasks)-1))
+		return nil
+	}
+	if p.options.Synchronous {
+		for _, task := range tasks {
+			p.runInline(task)
+		}
+		return nil
+	}
+
+	workers := make([]*goWorker, 0, len(tasks))
+	p.lock.Lock()
+	if p.IsClosed() {
+		p.lock.Unlock()
+		return ErrPoolClosed
+	}
+	idle := p.workers.Len()
+	if atomic.LoadPointer(&p.hotWorker) != nil {
+		idle++
+	}
+	spawn := len(tasks) - idle
+	if spawn < 0 {
+		spawn = 0
+	}
+	if capacity := p.Cap(); capacity != -1 && spawn > capacity-p.Running() {
+		p.lock.Unlock()
+		if secondary := p.options.OverflowPool; secondary != nil {
+			return secondary.SubmitNAtomic(tasks)
+		}
+		return ErrInsufficientCapacity
+	}
+	if l := p.spawnLimiter; l != nil {
+		for i := 0; i < spawn; i++ {
+			if !l.tryReserve() {
+				p.lock.Unlock()
+				return ErrInsufficientCapacity
+			}
+		}
+	}
+	for len(workers) < len(tasks)-spawn {
+		w := p.detachWorker()
+		if w == nil {
+			// hotWorker在检查之后被无锁的快速路径拿走了，用创建的worker补上
+			break
+		}
+		workers = append(workers, w)
+	}
+	for len(workers) < len(tasks) {
+		if capacity := p.Cap(); capacity != -1 && p.Running() >= capacity {
+			// 补上被拿走的hotWorker的时候已经没有容量了，把已经取出的worker还回去
+			p.lock.Unlock()
+			for _, w := range workers {
+				if !p.revertWorker(w) {
+					w.task <- nil
+				}
+			}
+			return ErrInsufficientCapacity
+		}
+		w := p.workerCache.Get().(*goWorker)
+		w.run()
+		workers = append(workers, w)
+	}
+	p.lock.Unlock()
+
+	for i, w := range workers {
+		run, _ := p.trackPending(tasks[i], tasks[i])
+		w.dispatch(run)
+	}
+	return nil
+}
+
+// SubmitInto 提交一个返回error的任务，任务结束后worker会把它返回的error（发生panic的时候是*PanicError，同时计入Panics()）发送到errc中，
+// 发送是非阻塞的，errc没有空间或者没有人在接收的时候结果会被丢弃并计入DroppedResults，
+// 所以errc应该带有足够的缓冲，或者在提交之前就有goroutine在接收
+func (p *Pool) SubmitInto(task func() error, errc chan<- error) error {
+	return p.Submit(func() {
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				p.recordPanic(r)
+				err = &PanicError{Value: r}
+			}
+			select {
+			case errc <- err:
+			default:
+				atomic.AddUint64(&p.droppedResults, 1)
+			}
+		}()
+		err = task()
+	})
+}
+
+// SpawnExact 提交n个任务并阻塞到它们全部同时运行起来，保证至少已经有n个worker goroutine创建好了，
+// 之后这些worker会回到空闲队列中，可以在基准测试或者对延迟敏感的初始化阶段预热pool。
+// n超过pool的容量的时候返回ErrSpawnExceedsCap，提交失败的时候返回对应的错误
+func (p *Pool) SpawnExact(n int) error {
+	if capacity := p.Cap(); capacity != -1 && n > capacity {
+		return ErrSpawnExceedsCap
+	}
+	var started sync.WaitGroup
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		// 每个任务都要等到所有任务都开始执行才返回，这样n个任务一定运行在不同的worker上
+		if err := p.Submit(func() {
+			started.Done()
+			<-release
+		}); err != nil {
+			started.Done()
+			return err
+		}
+	}
+	started.Wait()
 	return nil
 }
 
+// DroppedResults 返回SubmitInto因为errc无法立刻接收而丢弃的结果的数量
+func (p *Pool) DroppedResults() uint64 {
+	return atomic.LoadUint64(&p.droppedResults)
+}
+
 // Running 返回当前运行的goroutine的数量
 func (p *Pool) Running() int {
 	return int(atomic.LoadInt32(&p.running))
 }
 
-// Free 返回可用的goroutine的数量
+// Free 返回可用的goroutine的数量，同步的pool总是返回0，不限制容量的pool返回int的最大值，
+// 所以总是可以用Free() > 0判断还能不能创建goroutine
 func (p *Pool) Free() int {
+	if p.options.Synchronous {
+		return 0
+	}
+	if p.IsUnlimited() {
+		return maxInt
+	}
 	return p.Cap() - p.Running()
 }
 
+// InFlight 返回已经交给worker但还没有执行完的任务的数量，包括正在执行的和还在worker的任务channel中等待执行的，
+// 被MaxTaskDuration放弃的任务不再计入。和Running()不同，它不包括空闲的worker，更接近pool真实的负载
+func (p *Pool) InFlight() int {
+	return int(atomic.LoadInt32(&p.inFlight))
+}
+
+// LenIdle 返回空闲的worker的数量
+func (p *Pool) LenIdle() int {
+	p.lock.Lock()
+	idle := p.workers.Len()
+	p.lock.Unlock()
+	if atomic.LoadPointer(&p.hotWorker) != nil {
+		idle++
+	}
+	return idle
+}
+
+// IsUnlimited 返回pool是否不限制容量
+func (p *Pool) IsUnlimited() bool {
+	return p.Cap() == -1
+}
+
 // Cap 返回pool的容量
 func (p *Pool) Cap() int {
 	return int(atomic.LoadInt32(&p.capacity))
 }
 
+// SetNonblocking 在运行时切换阻塞和非阻塞模式，比如内存紧张的时候切换成非阻塞来丢弃超出的任务。
+// 切换成非阻塞的时候，阻塞在Submit中等待worker或者内存预算的提交者会被唤醒并返回ErrPoolOverload
+func (p *Pool) SetNonblocking(nonblocking bool) {
+	p.setNonblocking(nonblocking)
+	if !nonblocking {
+		return
+	}
+	p.lock.Lock()
+	p.cond.Broadcast()
+	p.lock.Unlock()
+	p.memLock.Lock()
+	p.memCond.Broadcast()
+	p.memLock.Unlock()
+}
+
+// isNonblocking 运行时是否非阻塞
+func (p *Pool) isNonblocking() bool {
+	return atomic.LoadInt32(&p.nonblocking) == 1
+}
+
+func (p *Pool) setNonblocking(nonblocking bool) {
+	var v int32
+	if nonblocking {
+		v = 1
+	}
+	atomic.StoreInt32(&p.nonblocking, v)
+}
+
 // Tune 改变pool的容量， 这个方法对无限制大小的pool是没有作用的
 func (p *Pool) Tune(size int) {
 	// capacity == -1
 	// size <= 0
 	// p.options.PreAlloc 预分配了大小
-	if capacity := p.Cap(); capacity == -1 || size <= 0 || size == capacity || p.options.PreAlloc {
+	capacity := p.Cap()
+	if capacity == -1 || size <= 0 || size == capacity || p.options.PreAlloc || p.options.Synchronous {
 		return
 	}
 	atomic.StoreInt32(&p.capacity, int32(size))
+	p.recordCapChange(capacity, size, 1)
+	// 扩容之后唤醒阻塞在retrieveWorker()中的调用者，让它们马上用新的容量创建worker，
+	// 在锁内唤醒，避免错过刚检查完容量还没有开始等待的调用者
+	if size > capacity {
+		p.lock.Lock()
+		p.wakeWaiters(size - capacity)
+		p.lock.Unlock()
+	}
+}
+
+// AdjustCapacity 把pool的容量原子地增加delta（delta为负数时减少），适合按增量调整容量的场景。
+// 不限制容量、PreAlloc或者同步的pool返回ErrCapacityFixed，新的容量不大于0的时候返回ErrInvalidPoolSize，
+// 小于Running()的时候返回ErrCapacityBelowRunning，这些情况下容量都不会改变
+func (p *Pool) AdjustCapacity(delta int) error {
+	if p.options.PreAlloc || p.options.Synchronous {
+		return ErrCapacityFixed
+	}
+	for {
+		capacity := atomic.LoadInt32(&p.capacity)
+		if capacity == -1 {
+			return ErrCapacityFixed
+		}
+		size := int(capacity) + delta
+		if size <= 0 {
+			return ErrInvalidPoolSize
+		}
+		if size < p.Running() {
+			return ErrCapacityBelowRunning
+		}
+		if atomic.CompareAndSwapInt32(&p.capacity, capacity, int32(size)) {
+			p.recordCapChange(int(capacity), size, 1)
+			// 和Tune一样，扩容之后唤醒阻塞在retrieveWorker()中的调用者
+			if delta > 0 {
+				p.lock.Lock()
+				p.wakeWaiters(delta)
+				p.lock.Unlock()
+			}
+			return nil
+		}
+	}
 }
 
 // IsClosed pool是否已经关闭
@@ -199,24 +922,355 @@ func (p *Pool) IsClosed() bool {
 
 // Release 关闭pool
 func (p *Pool) Release() {
+	// 在修改状态之前取出关闭信号，避免并发的Reboot换上新的关闭信号之后被这里取消
+	shutdown := p.shutdown.Load().(shutdownHolder)
 	//修改状态
 	atomic.StoreInt32(&p.state, CLOSED)
+	// 通知通过ShutdownContext等待关闭的任务
+	shutdown.cancel()
 	p.lock.Lock()
-	p.workers.reset()
+	p.workers.Reset()
+	if w := p.takeHotWorker(); w != nil {
+		w.task <- nil
+	}
 	p.lock.Unlock()
 	// 这里可能有一些调用者等待在retrieveWorker()，所以我们需要唤醒他，以防这些调用者永久的阻塞
 	p.cond.Broadcast()
+	p.notifyCapacity()
+	p.memLock.Lock()
+	p.memCond.Broadcast()
+	p.memLock.Unlock()
+	p.releasePinned()
+	p.dropDelayed()
+	p.closeWatchers()
+}
+
+// ReleaseContext 关闭pool并等待所有的worker和清理goroutine退出，ctx结束时还没有退出完的话返回ctx.Err()
+func (p *Pool) ReleaseContext(ctx context.Context) error {
+	p.Release()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for p.Running() > 0 || atomic.LoadInt32(&p.purging) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// ReleaseGraceful 关闭pool并等待所有的worker和清理goroutine退出，等待的过程中每当还在运行的worker的数量变化的时候调用onProgress，
+// 开始的时候和全部退出的时候（remaining为0）也各调用一次，可以用来显示关闭的进度。onProgress在调用ReleaseGraceful的goroutine中调用，
+// 每10ms最多一次；ctx结束时还没有退出完的话返回ctx.Err()
+func (p *Pool) ReleaseGraceful(ctx context.Context, onProgress func(remaining int)) error {
+	p.Release()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	last := -1
+	for {
+		remaining := p.Running()
+		if remaining != last && onProgress != nil {
+			onProgress(remaining)
+			last = remaining
+		}
+		if remaining == 0 && atomic.LoadInt32(&p.purging) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReleaseTimeout 关闭pool并最多等待d让所有的worker退出，超时的时候返回context.DeadlineExceeded，
+// d小于等于0的时候和Release一样立刻返回
+func (p *Pool) ReleaseTimeout(d time.Duration) error {
+	if d <= 0 {
+		p.Release()
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return p.ReleaseContext(ctx)
+}
+
+// Reboot 重启一个已经释放的pool，返回是否真的重启了，对没有关闭的pool调用什么也不做，返回false，
+// 和Release并发调用也是安全的
+func (p *Pool) Reboot() bool {
+	if !atomic.CompareAndSwapInt32(&p.state, CLOSED, OPENED) {
+		return false
+	}
+	epoch := atomic.AddUint32(&p.epoch, 1)
+	p.resizeProportional()
+	p.resetShutdown()
+	p.startMetricsSampler()
+	if !p.options.DisablePurge {
+		atomic.StoreInt64(&p.lastPurgeTime, time.Now().UnixNano())
+		p.startPurge(epoch)
+	}
+	return true
+}
+
+// RebootWith 在当前的配置上应用options之后重启pool，可以通过WithCapacity修改容量。
+// 新的配置先检查通过才会重启，检查不通过的时候pool保持关闭，修改PreAlloc、Synchronous或者LockProfiling会返回ErrIncompatibleOptions；
+// pool没有关闭或者上一次运行的worker和清理goroutine还没有全部退出的时候返回ErrRebootNotReady，可以先用ReleaseContext等待退出。
+// 不要和其他的Reboot方法并发调用
+func (p *Pool) RebootWith(options ...Option) error {
+	if !p.IsClosed() || p.Running() > 0 || atomic.LoadInt32(&p.alive) > 0 || atomic.LoadInt32(&p.purging) > 0 {
+		return ErrRebootNotReady
+	}
+	opts := *p.options
+	opts.Capacity = 0
+	opts.Nonblocking = p.isNonblocking()
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.PreAlloc != p.options.PreAlloc || opts.Synchronous != p.options.Synchronous ||
+		opts.LockProfiling != p.options.LockProfiling {
+		return ErrIncompatibleOptions
+	}
+	size := p.Cap()
+	if opts.Capacity != 0 {
+		size = opts.Capacity
+	}
+	size, err := prepareOptions(size, &opts)
+	if err != nil {
+		return err
+	}
+
+	// worker都已经退出，可以放心地替换它们会读到的字段
+	if opts.CapHistory != p.options.CapHistory {
+		p.capHistory = newCapHistory(opts.CapHistory)
+	}
+	p.options = &opts
+	p.recordCapChange(int(atomic.SwapInt32(&p.capacity, int32(size))), size, 1)
+	if opts.Capacity != 0 {
+		p.capFactor = 0
+	}
+	p.sources = newSourceScheduler(opts.SourceWeights)
+	p.stormGuard = nil
+	if opts.PanicStormThreshold > 0 && opts.PanicStormWindow > 0 {
+		p.stormGuard = newPanicStormGuard(opts.PanicStormThreshold, opts.PanicStormWindow)
+	}
+	p.spawnLimiter = newSpawnLimiter(opts.SpawnRateLimit)
+	p.setNonblocking(opts.Nonblocking)
+	p.taskChanCap = taskChanCap(size, opts.MaxTaskBufferBytes, unsafe.Sizeof(func() {}))
+	if opts.BaseContext != nil {
+		p.SetBaseContext(opts.BaseContext)
+	}
+	p.lock.Lock()
+	if opts.PreAlloc {
+		p.workers = NewWorkerArray(LoopQueueType, size)
+	} else {
+		p.workers.Reset()
+	}
+	p.lock.Unlock()
+
+	if !p.Reboot() {
+		return ErrRebootNotReady
+	}
+	p.spawnInitialWorkers()
+	return nil
+}
+
+// warmWorkerCache 预先创建WarmWorkerCache个goWorker放到workerCache中
+func (p *Pool) warmWorkerCache() {
+	for i := 0; i < p.options.WarmWorkerCache; i++ {
+		p.workerCache.Put(p.workerCache.New())
+	}
+}
+
+// spawnInitialWorkers 预先启动InitialWorkers个空闲的worker
+func (p *Pool) spawnInitialWorkers() {
+	for i := 0; i < p.options.InitialWorkers; i++ {
+		w := p.workerCache.Get().(*goWorker)
+		w.run()
+		if !p.revertWorker(w) {
+			w.task <- nil
+		}
+	}
+}
+
+// RebootWhenDrained 等待上一次运行的所有worker goroutine都退出之后再重启pool，保证重启之后的计数是干净的，
+// timeout之内没有退出完的时候不会重启，返回context.DeadlineExceeded
+func (p *Pool) RebootWhenDrained(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for p.Running() > 0 || atomic.LoadInt32(&p.alive) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	p.Reboot()
+	return nil
+}
+
+// PurgeStale 清理goroutine超过threshold没有运行的时候返回true，说明它可能已经退出了，
+// pool已经关闭或者关闭了定期清理的时候总是返回false
+func (p *Pool) PurgeStale(threshold time.Duration) bool {
+	if p.IsClosed() || p.options.DisablePurge {
+		return false
+	}
+	last := atomic.LoadInt64(&p.lastPurgeTime)
+	return time.Since(time.Unix(0, last)) > threshold
+}
+
+// SwapWorkerArray 在运行时替换存放空闲worker的容器，正在运行任务的worker不受影响，
+// 所有空闲的worker都会被迁移到wa中，wa放不下的worker会被停止，返回被替换掉的容器，wa为nil的时候返回ErrNilWorkerArray
+func (p *Pool) SwapWorkerArray(wa WorkerArray) (WorkerArray, error) {
+	if wa == nil {
+		return nil, ErrNilWorkerArray
+	}
+	p.lock.Lock()
+	old, overflow := p.swapWorkerArray(wa)
+	p.lock.Unlock()
+	stopWorkers(overflow)
+	return old, nil
+}
+
+// MigrateTo 修改pool的容量，和Tune不同的是它对PreAlloc的pool也生效：
+// 会创建一个新容量的环形队列，把空闲的worker迁移过去再替换掉旧的，迁移过程中的Submit会在pool.lock上短暂地等待
+func (p *Pool) MigrateTo(newSize int) error {
+	if newSize <= 0 {
+		return ErrInvalidPoolSize
+	}
+	if p.IsClosed() {
+		return ErrPoolClosed
+	}
+	if !p.options.PreAlloc {
+		p.Tune(newSize)
+		return nil
+	}
+
+	p.lock.Lock()
+	var overflow []*goWorker
+	if newSize < p.Cap() {
+		// 缩容的时候原地缩小环形队列，释放多出来的槽位
+		overflow = p.shrinkWorkerArray(newSize)
+	} else {
+		_, overflow = p.swapWorkerArray(NewWorkerArray(LoopQueueType, newSize))
+	}
+	capacity := atomic.SwapInt32(&p.capacity, int32(newSize))
+	p.recordCapChange(int(capacity), newSize, 1)
+	// 扩容之后阻塞在retrieveWorker()中的调用者可以直接创建新的worker了
+	if newSize > int(capacity) {
+		p.wakeWaiters(newSize - int(capacity))
+	}
+	p.lock.Unlock()
+	stopWorkers(overflow)
+	return nil
+}
+
+// Shrink 把pool的容量减小到newSize，并立刻停止超出newSize的空闲worker，返回立刻停止的worker的数量。
+// 正在运行任务的worker不受影响，它们完成任务后发现Running()超过了容量就会退出，Running()降到newSize以下之前不会创建新的worker。
+// newSize不小于当前容量、pool容量不限制、同步的或者已经关闭的时候什么也不做
+func (p *Pool) Shrink(newSize int) int {
+	capacity := p.Cap()
+	if newSize <= 0 || capacity == -1 || newSize >= capacity || p.options.Synchronous || p.IsClosed() {
+		return 0
+	}
+
+	var evicted []*goWorker
+	p.lock.Lock()
+	atomic.StoreInt32(&p.capacity, int32(newSize))
+	p.recordCapChange(capacity, newSize, 1)
+	for excess := p.Running() - newSize; len(evicted) < excess; {
+		w := p.detachWorker()
+		if w == nil {
+			break
+		}
+		evicted = append(evicted, w)
+	}
+	// 预先分配的环形队列也要缩小到新的容量
+	if p.options.PreAlloc {
+		evicted = append(evicted, p.shrinkWorkerArray(newSize)...)
+	}
+	p.lock.Unlock()
+
+	n := len(evicted)
+	stopWorkers(evicted)
+	return n
+}
+
+// swapWorkerArray 把空闲的worker迁移到空的wa中并替换掉p.workers，返回旧的容器和wa中放不下的worker，需要持有pool.lock。
+// wa放不下的时候保留最近归还的worker，迁移之后wa中的worker仍然按recycleTime从早到晚排列，过期清理依赖这个顺序
+func (p *Pool) swapWorkerArray(wa WorkerArray) (old WorkerArray, overflow []*goWorker) {
+	idleWorkers := make([]*goWorker, 0, p.workers.Len())
+	for w := p.workers.Detach(); w != nil; w = p.workers.Detach() {
+		idleWorkers = append(idleWorkers, w)
+	}
+	// 从晚到早排序，先试出wa能放下多少个
+	sort.Slice(idleWorkers, func(i, j int) bool {
+		return idleWorkers[i].recycleTime > idleWorkers[j].recycleTime
+	})
+	n := 0
+	for _, w := range idleWorkers {
+		if err := wa.Insert(w); err != nil {
+			break
+		}
+		n++
+	}
+	for wa.Detach() != nil {
+	}
+	// 再按从早到晚的顺序放回去
+	for i := n - 1; i >= 0; i-- {
+		_ = wa.Insert(idleWorkers[i])
+	}
+	if n < len(idleWorkers) {
+		overflow = idleWorkers[n:]
+	}
+	old = p.workers
+	p.workers = wa
+	return
 }
 
-// Reboot 重启一个已经释放的pool
-func (p *Pool) Reboot() {
-	if atomic.CompareAndSwapInt32(&p.state, CLOSED, OPENED) {
-		go p.purgePeriodically()
+// shrinkWorkerArray 把预先分配的环形队列原地缩小到newSize，释放多出来的槽位，放不下的空闲worker从最早归还的开始取出并返回，
+// 和swapWorkerArray一样保留最近归还的worker，需要持有pool.lock
+func (p *Pool) shrinkWorkerArray(newSize int) (overflow []*goWorker) {
+	q, ok := p.workers.(*loopQueue)
+	if !ok {
+		return nil
+	}
+	for q.Len() > newSize {
+		overflow = append(overflow, q.Detach())
+	}
+	_ = q.shrink(newSize)
+	return
+}
+
+// stopWorkers 通知workers退出，和purgePeriodically一样，需要在pool.lock之外调用
+func stopWorkers(workers []*goWorker) {
+	for i := range workers {
+		workers[i].task <- nil
+		workers[i] = nil
 	}
 }
 
 // ---------------------------------------------------------------------------
 
+// runInline 在调用者的goroutine中直接执行任务，执行期间计入Running()，
+// 设置了PanicHandler的时候由它处理panic，否则panic会抛给调用者
+func (p *Pool) runInline(task func()) {
+	p.incRunning()
+	defer func() {
+		p.decRunning()
+		if ph := p.options.PanicHandler; ph != nil {
+			if r := recover(); r != nil {
+				p.recordPanic(r)
+				ph(r)
+			}
+		}
+	}()
+	task()
+}
+
 // incRunning 递增当前运行的goroutine的数量
 func (p *Pool) incRunning() {
 	atomic.AddInt32(&p.running, 1)
@@ -227,18 +1281,49 @@ func (p *Pool) decRunning() {
 	atomic.AddInt32(&p.running, -1)
 }
 
-// retrieveWorker 返回一个可用的worker来运行任务
-func (p *Pool) retrieveWorker() (w *goWorker) {
+// takeHotWorker 无锁地取出hotWorker，没有开启HotWorker或者槽位为空的时候返回nil
+func (p *Pool) takeHotWorker() *goWorker {
+	if !p.options.HotWorker {
+		return nil
+	}
+	return (*goWorker)(atomic.SwapPointer(&p.hotWorker, nil))
+}
+
+// detachWorker 从workers或者hotWorker中取出一个空闲的worker，需要持有pool.lock
+func (p *Pool) detachWorker() *goWorker {
+	if w := p.workers.Detach(); w != nil {
+		return w
+	}
+	return p.takeHotWorker()
+}
+
+// retrieveWorker 返回一个可用的worker来运行任务，阻塞等待的过程中ctx被取消或者被CancelPending取消的话会返回nil
+func (p *Pool) retrieveWorker(ctx context.Context) *goWorker {
+	w, _ := p.retrieveWorkerOrCancel(ctx)
+	return w
+}
+
+// retrieveWorkerOrCancel 和retrieveWorker一样，另外返回阻塞等待的过程中是否被CancelPending取消
+func (p *Pool) retrieveWorkerOrCancel(ctx context.Context) (w *goWorker, cancelled bool) {
 	// 获取一个worker
 	spawnWorker := func() {
+		// 设置了SpawnRateLimit的时候等到允许创建为止，放弃的话返回nil
+		if !p.waitSpawn(ctx) {
+			return
+		}
 		// 从workerCache中获取一个可用的worker，如果没有就会使用预设的New创建一个
 		w = p.workerCache.Get().(*goWorker)
 		w.run()
 	}
 
+	// 快速路径：不加锁直接拿最近归还的worker
+	if w = p.takeHotWorker(); w != nil {
+		return
+	}
+
 	p.lock.Lock()
 	// 获取一个可用的worker
-	w = p.workers.detach()
+	w = p.detachWorker()
 	if w != nil {
 		// 获得到一个可用的worker
 		p.lock.Unlock()
@@ -252,22 +1337,97 @@ func (p *Pool) retrieveWorker() (w *goWorker) {
 		spawnWorker()
 	} else {
 		//如果是非阻塞的
-		if p.options.Nonblocking {
+		if p.isNonblocking() {
 			p.lock.Unlock()
+			p.markFull()
 			return
 		}
+		// 先自旋等待一会儿，worker很快就会空闲出来的时候可以避免阻塞和唤醒的开销
+		for i := 0; i < p.options.SpinWaitOnFull && ctx.Err() == nil; i++ {
+			p.lock.Unlock()
+			runtime.Gosched()
+			p.lock.Lock()
+			if p.IsClosed() {
+				p.lock.Unlock()
+				return
+			}
+			if w = p.detachWorker(); w != nil {
+				p.lock.Unlock()
+				return
+			}
+			if p.Running() < p.Cap() {
+				p.lock.Unlock()
+				spawnWorker()
+				return
+			}
+		}
+		// 设置了MaxBlockingDuration的时候，到时间之后唤醒所有等待者，让自己放弃等待
+		var blockingTimedOut int32
+		if d := p.options.MaxBlockingDuration; d > 0 {
+			timer := time.AfterFunc(d, func() {
+				atomic.StoreInt32(&blockingTimedOut, 1)
+				p.lock.Lock()
+				p.cond.Broadcast()
+				p.lock.Unlock()
+			})
+			defer timer.Stop()
+		}
 	Reentry:
-		if p.options.MaxBlockingTasks != 0 && p.blockingNum >= p.options.MaxBlockingTasks {
-			// MaxBlockingTasks已经设置并且不等于0 && 阻塞的个数 大于等于 允许的最大的阻塞数，就直接返回
+		// 等待的过程中pool被关闭，不再等待还在运行的worker归还
+		if p.IsClosed() {
+			p.lock.Unlock()
+			return
+		}
+		// 等待的过程中被SetNonblocking切换成了非阻塞
+		if p.isNonblocking() {
+			p.cond.Signal()
+			p.lock.Unlock()
+			p.markFull()
+			return
+		}
+		// 在锁内检查ctx，保证不会错过ctx取消时的Broadcast；
+		// 放弃等待的调用者可能刚被wakeWaiters唤醒，把唤醒转交给下一个等待者，避免可以创建的worker没人创建
+		if ctx.Err() != nil {
+			p.cond.Signal()
 			p.lock.Unlock()
 			return
 		}
+		if atomic.LoadInt32(&blockingTimedOut) == 1 {
+			p.cond.Signal()
+			p.lock.Unlock()
+			atomic.AddUint64(&p.blockingTimeouts, 1)
+			p.markFull()
+			return
+		}
+		if p.options.MaxBlockingTasks != 0 && int(atomic.LoadInt32(&p.blockingNum)) >= p.options.MaxBlockingTasks && !ignoreBlockingLimit(ctx) {
+			// MaxBlockingTasks已经设置并且不等于0 && 阻塞的个数 大于等于 允许的最大的阻塞数，就直接返回（SubmitOrBlock除外）
+			p.lock.Unlock()
+			p.markFull()
+			return
+		}
 		// 阻塞
-		p.blockingNum++
+		atomic.AddInt32(&p.blockingNum, 1)
+		// 增加blockingNum之后再检查一次hotWorker，和revertWorker的快速路径配合，保证不会错过归还的worker
+		if w = p.takeHotWorker(); w != nil {
+			atomic.AddInt32(&p.blockingNum, -1)
+			p.lock.Unlock()
+			return
+		}
 		// 加入等待队列
+		gen := p.cancelGen
+		p.pendingWaiters++
 		p.cond.Wait()
+		p.pendingWaiters--
 
-		p.blockingNum--
+		atomic.AddInt32(&p.blockingNum, -1)
+		if p.cancelGen != gen {
+			// 被CancelPending取消，可能拿走了归还worker时给其他等待者的唤醒，转交出去
+			if p.workerAvailable() {
+				p.cond.Signal()
+			}
+			p.lock.Unlock()
+			return nil, true
+		}
 		var nw int
 		// 当前运行的worker为0个
 		if nw = p.Running(); nw == 0 {
@@ -279,9 +1439,9 @@ func (p *Pool) retrieveWorker() (w *goWorker) {
 			return
 		}
 		// 再次尝试从workers中获取一个，但是没有获得到
-		if w = p.workers.detach(); w == nil {
-			// 运行的数量小于容量的时候
-			if nw < capacity {
+		if w = p.detachWorker(); w == nil {
+			// 运行的数量小于容量的时候，容量可能在等待的过程中被MigrateTo修改，需要重新读取
+			if nw < p.Cap() {
 				p.lock.Unlock()
 				spawnWorker()
 				return
@@ -295,13 +1455,87 @@ func (p *Pool) retrieveWorker() (w *goWorker) {
 	return
 }
 
+// CancelPending 取消所有阻塞在Submit中等待worker的提交，它们的任务不会执行，Submit返回ErrTaskCancelled，
+// 返回取消的提交的数量。之后的提交不受影响；阻塞在SubmitWithPriority、SubmitFromSource中的提交不会被取消
+func (p *Pool) CancelPending() int {
+	p.lock.Lock()
+	n := p.pendingWaiters
+	if n > 0 {
+		p.cancelGen++
+		p.cond.Broadcast()
+	}
+	p.lock.Unlock()
+	return n
+}
+
+// wakeWaiters 唤醒n个阻塞在retrieveWorker()中的调用者，n不小于等待者的数量或者小于0的时候唤醒所有的，需要持有pool.lock。
+// 只唤醒能拿到worker的数量，避免所有的等待者同时醒来去争抢pool.lock，大部分又只能重新等待（惊群），
+// 没有被唤醒的等待者在之后有worker归还的时候由revertWorker逐个唤醒
+func (p *Pool) wakeWaiters(n int) {
+	if atomic.LoadInt32(&p.capacityWaiters) > 0 {
+		p.capacityCond.Broadcast()
+	}
+	if n < 0 || n >= int(atomic.LoadInt32(&p.blockingNum)) {
+		p.cond.Broadcast()
+		return
+	}
+	for i := 0; i < n; i++ {
+		p.cond.Signal()
+	}
+}
+
+// tryRetrieveWorker 和Nonblocking时的retrieveWorker一样，没有可用的worker并且不能再创建的时候直接返回nil
+func (p *Pool) tryRetrieveWorker() (w *goWorker) {
+	if w = p.takeHotWorker(); w != nil {
+		return
+	}
+	p.lock.Lock()
+	if w = p.detachWorker(); w != nil {
+		p.lock.Unlock()
+		return
+	}
+	if capacity := p.Cap(); capacity == -1 || p.Running() < capacity {
+		p.lock.Unlock()
+		if l := p.spawnLimiter; l != nil && !l.tryReserve() {
+			return nil
+		}
+		w = p.workerCache.Get().(*goWorker)
+		w.run()
+		return
+	}
+	p.lock.Unlock()
+	p.markFull()
+	return nil
+}
+
 // revertWorker 将worker归还到pool中，重复使用goroutine
 func (p *Pool) revertWorker(worker *goWorker) bool {
 	// pool不是无限容量的，并且已经运行的worker数量已经超过了pool的容量了或者pool已经关闭的情绪，就直接返回
 	if capacity := p.Cap(); (capacity > 0 && p.Running() > capacity) || p.IsClosed() {
 		return false
 	}
-	worker.recycleTime = time.Now()
+	// 禁止复用的时候worker执行完一个任务就退出
+	if p.options.NoReuse {
+		return false
+	}
+	worker.recycleTime = nanotime()
+
+	// 快速路径：槽位为空的话不加锁直接放到hotWorker中
+	if p.options.HotWorker && atomic.CompareAndSwapPointer(&p.hotWorker, nil, unsafe.Pointer(worker)) {
+		// 和下面的双检测一样，pool已经关闭的话把worker取回来让它退出，取不回来说明Release已经通知它退出了
+		if p.IsClosed() && atomic.CompareAndSwapPointer(&p.hotWorker, unsafe.Pointer(worker), nil) {
+			return false
+		}
+		// 有调用者阻塞在retrieveWorker()中的时候唤醒它
+		if atomic.LoadInt32(&p.blockingNum) > 0 {
+			p.lock.Lock()
+			p.cond.Signal()
+			p.lock.Unlock()
+		}
+		p.notifyCapacity()
+		return true
+	}
+
 	p.lock.Lock()
 
 	// 避免内存的泄漏，在锁范围内增加了一个双检测
@@ -311,7 +1545,7 @@ func (p *Pool) revertWorker(worker *goWorker) bool {
 		return false
 	}
 	// 将worker插入workers中
-	err := p.workers.insert(worker)
+	err := p.workers.Insert(worker)
 	if err != nil {
 		p.lock.Unlock()
 		return false
@@ -319,6 +1553,52 @@ func (p *Pool) revertWorker(worker *goWorker) bool {
 
 	// 归还完之后，提醒卡在了'retrieveWorker()' 的调用者，现在有一个可用的worker了
 	p.cond.Signal()
+	if atomic.LoadInt32(&p.capacityWaiters) > 0 {
+		p.capacityCond.Broadcast()
+	}
 	p.lock.Unlock()
+	p.markNotFull()
 	return true
 }
+
+// markFull 在因为pool已满而拒绝任务的时候调用，从未满变成已满的时候，
+// 在onFullDebounce之后仍然是满的才会调用OnFull，避免在满和不满之间快速切换时频繁地调用
+func (p *Pool) markFull() {
+	if p.options.OnFull == nil || !atomic.CompareAndSwapInt32(&p.full, 0, 1) {
+		return
+	}
+	gen := atomic.AddUint32(&p.fullGen, 1)
+	time.AfterFunc(onFullDebounce, func() {
+		if atomic.LoadInt32(&p.full) == 1 && atomic.LoadUint32(&p.fullGen) == gen {
+			p.options.OnFull()
+		}
+	})
+}
+
+// markNotFull 在有worker空闲下来或者退出的时候调用
+func (p *Pool) markNotFull() {
+	if atomic.LoadInt32(&p.full) == 1 {
+		atomic.StoreInt32(&p.full, 0)
+	}
+}
+
+// taskStarted 在worker开始执行任务的时候调用，执行任务的worker达到容量的时候触发一次OnSaturated
+func (p *Pool) taskStarted() {
+	busy := atomic.AddInt32(&p.busy, 1)
+	if capacity := p.Cap(); capacity > 0 && int(busy) >= capacity && atomic.CompareAndSwapInt32(&p.saturated, 0, 1) {
+		if onSaturated := p.options.OnSaturated; onSaturated != nil {
+			onSaturated()
+		}
+	}
+}
+
+// taskFinished 在worker执行完任务的时候调用，之前已经饱和的话触发一次OnRecovered
+func (p *Pool) taskFinished() {
+	atomic.AddInt32(&p.inFlight, -1)
+	atomic.AddInt32(&p.busy, -1)
+	if atomic.CompareAndSwapInt32(&p.saturated, 1, 0) {
+		if onRecovered := p.options.OnRecovered; onRecovered != nil {
+			onRecovered()
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/panjf2000/ants/v2/internal"
 )
@@ -44,6 +45,9 @@ type PoolWithFunc struct {
 	// state is used to notice the pool to closed itself.
 	state int32
 
+	// epoch 每次Reboot成功的时候加1，清理goroutine发现和启动它时的不一样的时候退出
+	epoch uint32
+
 	// lock for synchronous operation.
 	lock sync.Locker
 
@@ -60,25 +64,31 @@ type PoolWithFunc struct {
 	blockingNum int
 
 	options *Options
+
+	// cpuCursor 开启CPUAffinity的时候，用来轮流给worker分配CPU
+	cpuCursor uint32
+
+	// taskChanCap 每个worker的参数channel的缓冲大小，创建pool的时候根据MaxTaskBufferBytes确定
+	taskChanCap int
 }
 
 // purgePeriodically 定期清除过期的workers，它会单独运行一个goroutine作为清理者
-func (p *PoolWithFunc) purgePeriodically() {
+func (p *PoolWithFunc) purgePeriodically(epoch uint32) {
 	heartbeat := time.NewTicker(p.options.ExpiryDuration)
 	defer heartbeat.Stop()
 
 	var expiredWorkers []*goWorkerWithFunc
 	for range heartbeat.C {
-		if p.IsClosed() {
+		if p.IsClosed() || atomic.LoadUint32(&p.epoch) != epoch {
 			break
 		}
-		currentTime := time.Now()
+		currentTime := nanotime()
 		p.lock.Lock()
 		idleWorkers := p.workers
 		n := len(idleWorkers)
 		var i int
 		// 统计过期的worker的个数
-		for i = 0; i < n && currentTime.Sub(idleWorkers[i].recycleTime) > p.options.ExpiryDuration; i++ {
+		for i = 0; i < n && time.Duration(currentTime-idleWorkers[i].recycleTime) > p.options.ExpiryDuration; i++ {
 		}
 		// 将idleWorkers中过期的worker加入到过期的expiredWorkers中
 		expiredWorkers = append(expiredWorkers[:0], idleWorkers[:i]...)
@@ -137,10 +147,12 @@ func NewPoolWithFunc(size int, pf func(interface{}), options ...Option) (*PoolWi
 		lock:     internal.NewSpinLock(),
 		options:  opts,
 	}
+	var arg interface{}
+	p.taskChanCap = taskChanCap(size, opts.MaxTaskBufferBytes, unsafe.Sizeof(arg))
 	p.workerCache.New = func() interface{} {
 		return &goWorkerWithFunc{
 			pool: p,
-			args: make(chan interface{}, workerChanCap),
+			args: make(chan interface{}, p.taskChanCap),
 		}
 	}
 	if p.options.PreAlloc {
@@ -149,7 +161,9 @@ func NewPoolWithFunc(size int, pf func(interface{}), options ...Option) (*PoolWi
 	p.cond = sync.NewCond(p.lock)
 
 	// 使用一个goroutine来清理过期的workers
-	go p.purgePeriodically()
+	if !p.options.DisablePurge {
+		go p.purgePeriodically(0)
+	}
 
 	return p, nil
 }
@@ -186,10 +200,17 @@ func (p *PoolWithFunc) Cap() int {
 
 // Tune changes the capacity of this pool.
 func (p *PoolWithFunc) Tune(size int) {
-	if size <= 0 || size == p.Cap() || p.options.PreAlloc {
+	capacity := p.Cap()
+	if size <= 0 || size == capacity || p.options.PreAlloc {
 		return
 	}
 	atomic.StoreInt32(&p.capacity, int32(size))
+	// 扩容之后唤醒阻塞在retrieveWorker()中的调用者
+	if size > capacity {
+		p.lock.Lock()
+		p.cond.Broadcast()
+		p.lock.Unlock()
+	}
 }
 
 // IsClosed indicates whether the pool is closed.
@@ -211,11 +232,16 @@ func (p *PoolWithFunc) Release() {
 	p.cond.Broadcast()
 }
 
-// Reboot 重启一个已经释放的pool
-func (p *PoolWithFunc) Reboot() {
-	if atomic.CompareAndSwapInt32(&p.state, CLOSED, OPENED) {
-		go p.purgePeriodically()
+// Reboot 重启一个已经释放的pool，返回是否真的重启了，对没有关闭的pool调用什么也不做，返回false
+func (p *PoolWithFunc) Reboot() bool {
+	if !atomic.CompareAndSwapInt32(&p.state, CLOSED, OPENED) {
+		return false
+	}
+	epoch := atomic.AddUint32(&p.epoch, 1)
+	if !p.options.DisablePurge {
+		go p.purgePeriodically(epoch)
 	}
+	return true
 }
 
 //---------------------------------------------------------------------------
@@ -303,7 +329,10 @@ func (p *PoolWithFunc) revertWorker(worker *goWorkerWithFunc) bool {
 		// 运行的goroutine数超过了容量 或者 pool已经关闭了
 		return false
 	}
-	worker.recycleTime = time.Now()
+	if p.options.NoReuse {
+		return false
+	}
+	worker.recycleTime = nanotime()
 	p.lock.Lock()
 
 	// 避免内存的泄漏，在锁范围内增加了一个双检测
@@ -0,0 +1,135 @@
+package ants
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// priorityWaiter 是一个阻塞在SubmitWithPriority上的提交
+type priorityWaiter struct {
+	priority int
+	evicted  bool
+}
+
+// SubmitWithPriority 提交一个带优先级的任务，数值越大优先级越高，没有开启PreemptiveQueue的时候和Submit一样。
+// 开启之后pool已满并且阻塞的提交者已经达到MaxBlockingTasks的时候，会挤掉一个优先级比自己低的、还在等待worker的提交，
+// 被挤掉的提交返回ErrPreempted，它的任务交给RejectHandler；找不到优先级更低的提交的时候返回ErrPoolOverload
+func (p *Pool) SubmitWithPriority(priority int, task func()) error {
+	if !p.options.PreemptiveQueue || p.isNonblocking() || p.options.Synchronous {
+		return p.Submit(task)
+	}
+	if dropped, err := p.admit(); dropped || err != nil {
+		return err
+	}
+	run, untrack := p.trackPending(task, task)
+	w, err := p.retrieveWorkerWithPriority(priority)
+	if err != nil {
+		untrack()
+	}
+	switch err {
+	case nil:
+	case ErrPreempted:
+		if reject := p.options.RejectHandler; reject != nil {
+			reject(task)
+		}
+		return err
+	case ErrPoolOverload:
+		return p.overflow(task)
+	default:
+		return err
+	}
+	w.dispatch(run)
+	return nil
+}
+
+// retrieveWorkerWithPriority 获取一个worker，需要等待而等待的位置已满的时候尝试抢占优先级更低的等待者
+func (p *Pool) retrieveWorkerWithPriority(priority int) (*goWorker, error) {
+	var self *priorityWaiter
+	p.lock.Lock()
+	for {
+		if self != nil && self.evicted {
+			// 被抢占的时候已经从等待队列中移除，blockingNum也已经减掉了，
+			// 唤醒自己的可能是归还worker时的Signal，需要传递给其它等待者
+			if p.workerAvailable() {
+				p.cond.Signal()
+			}
+			p.lock.Unlock()
+			return nil, ErrPreempted
+		}
+		if p.IsClosed() {
+			p.removeWaiter(self)
+			p.lock.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if w := p.detachWorker(); w != nil {
+			p.removeWaiter(self)
+			p.lock.Unlock()
+			return w, nil
+		}
+		if capacity := p.Cap(); capacity == -1 || p.Running() < capacity {
+			p.removeWaiter(self)
+			p.lock.Unlock()
+			if !p.waitSpawn(context.Background()) {
+				p.markFull()
+				return nil, ErrPoolOverload
+			}
+			w := p.workerCache.Get().(*goWorker)
+			w.run()
+			return w, nil
+		}
+		if p.isNonblocking() {
+			// 等待的过程中被SetNonblocking切换成了非阻塞
+			p.removeWaiter(self)
+			p.lock.Unlock()
+			p.markFull()
+			return nil, ErrPoolOverload
+		}
+		if self == nil {
+			if max := p.options.MaxBlockingTasks; max != 0 && int(atomic.LoadInt32(&p.blockingNum)) >= max {
+				if !p.evictWaiter(priority) {
+					p.lock.Unlock()
+					p.markFull()
+					return nil, ErrPoolOverload
+				}
+			}
+			self = &priorityWaiter{priority: priority}
+			p.waiters = append(p.waiters, self)
+			atomic.AddInt32(&p.blockingNum, 1)
+			// 增加blockingNum之后再检查一次，和retrieveWorker一样保证不会错过revertWorker快速路径归还的worker
+			continue
+		}
+		p.cond.Wait()
+	}
+}
+
+// removeWaiter 把w从等待队列中移除，需要持有pool.lock
+func (p *Pool) removeWaiter(w *priorityWaiter) {
+	if w == nil {
+		return
+	}
+	for i, waiter := range p.waiters {
+		if waiter == w {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			atomic.AddInt32(&p.blockingNum, -1)
+			return
+		}
+	}
+}
+
+// evictWaiter 挤掉优先级最低并且低于priority的等待者，优先级相同的时候挤掉最后开始等待的，需要持有pool.lock
+func (p *Pool) evictWaiter(priority int) bool {
+	victim := -1
+	for i, w := range p.waiters {
+		if w.priority < priority && (victim == -1 || w.priority <= p.waiters[victim].priority) {
+			victim = i
+		}
+	}
+	if victim == -1 {
+		return false
+	}
+	p.waiters[victim].evicted = true
+	p.removeWaiter(p.waiters[victim])
+	// 唤醒被挤掉的提交，让它返回
+	p.cond.Broadcast()
+	return true
+}
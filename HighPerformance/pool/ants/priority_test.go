@@ -0,0 +1,59 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitWithPriority(t *testing.T) {
+	var rejected int32
+	p, err := NewPool(1, WithPreemptiveQueue(true), WithMaxBlockingTasks(2), WithRejectHandler(func(task func()) {
+		atomic.AddInt32(&rejected, 1)
+		task()
+	}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// pool已满，两个低优先级的提交占满了等待的位置
+	p.incRunning()
+	defer p.decRunning()
+	var evictedTask int32
+	errs := make(chan error, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- p.SubmitWithPriority(1, func() { atomic.AddInt32(&evictedTask, 1) })
+		}()
+	}
+	for atomic.LoadInt32(&p.blockingNum) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 优先级相同的提交挤不掉它们
+	assert.Equal(t, ErrPoolOverload, p.SubmitWithPriority(1, demoFunc))
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- p.SubmitWithPriority(5, demoFunc)
+	}()
+	assert.Equal(t, ErrPreempted, <-errs, "a low-priority submission should be evicted")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&rejected))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&evictedTask), "evicted task should be handed to the reject handler")
+	for atomic.LoadInt32(&p.blockingNum) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	p.Release()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Equal(t, ErrPoolClosed, err)
+	}
+}
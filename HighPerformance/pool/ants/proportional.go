@@ -0,0 +1,40 @@
+package ants
+
+import (
+	"math"
+	"runtime"
+	"sync/atomic"
+)
+
+// NewPoolProportional 创建一个容量为round(factor*GOMAXPROCS)的pool，至少为1，同样的配置在不同的机器上自动适应CPU的数量。
+// factor必须大于0，否则返回ErrInvalidPoolSize。之后Reboot的时候如果GOMAXPROCS变了，会按新的值重新计算容量（PreAlloc的pool除外），
+// 通过Tune等方法修改过的容量也会被覆盖；RebootWith指定了WithCapacity之后不再按比例计算
+func NewPoolProportional(factor float64, options ...Option) (*Pool, error) {
+	if !(factor > 0) || math.IsInf(factor, 1) {
+		return nil, ErrInvalidPoolSize
+	}
+	p, err := NewPool(proportionalSize(factor), options...)
+	if err != nil {
+		return nil, err
+	}
+	p.capFactor = factor
+	return p, nil
+}
+
+// proportionalSize 返回round(factor*GOMAXPROCS)，至少为1
+func proportionalSize(factor float64) int {
+	size := int(math.Round(factor * float64(runtime.GOMAXPROCS(0))))
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// resizeProportional 在Reboot的时候按当前的GOMAXPROCS重新计算NewPoolProportional创建的pool的容量
+func (p *Pool) resizeProportional() {
+	if p.capFactor <= 0 || p.options.PreAlloc || p.options.Synchronous {
+		return
+	}
+	size := proportionalSize(p.capFactor)
+	p.recordCapChange(int(atomic.SwapInt32(&p.capacity, int32(size))), size, 2)
+}
@@ -0,0 +1,47 @@
+package ants
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPoolProportional(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	for _, factor := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		_, err := NewPoolProportional(factor)
+		assert.Equal(t, ErrInvalidPoolSize, err, "factor %v should be rejected", factor)
+	}
+
+	p, err := NewPoolProportional(1.5)
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.Equal(t, 6, p.Cap())
+
+	small, err := NewPoolProportional(0.1)
+	assert.NoError(t, err)
+	defer small.Release()
+	assert.Equal(t, 1, small.Cap(), "capacity should be at least 1")
+
+	// Reboot的时候按新的GOMAXPROCS重新计算
+	runtime.GOMAXPROCS(2)
+	assert.NoError(t, p.ReleaseContext(context.Background()))
+	assert.True(t, p.Reboot())
+	assert.Equal(t, 3, p.Cap())
+
+	// RebootWith指定了容量之后不再按比例计算
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, p.ReleaseContext(ctx))
+	assert.NoError(t, p.RebootWith(WithCapacity(10)))
+	assert.Equal(t, 10, p.Cap())
+	runtime.GOMAXPROCS(4)
+	assert.NoError(t, p.ReleaseContext(ctx))
+	assert.True(t, p.Reboot())
+	assert.Equal(t, 10, p.Cap())
+}
@@ -0,0 +1,84 @@
+package ants
+
+import "time"
+
+// RetryPolicy 决定失败的任务重试几次、每次重试之前等待多久
+type RetryPolicy struct {
+	// MaxAttempts 最多执行的次数（包括第一次），小于等于0的时候只执行一次
+	MaxAttempts int
+
+	// Backoff 第一次重试之前等待的时间
+	Backoff time.Duration
+
+	// Multiplier 大于1的时候，之后每次重试的等待时间都乘以Multiplier
+	Multiplier float64
+
+	// MaxBackoff 大于0的时候，等待时间不超过MaxBackoff
+	MaxBackoff time.Duration
+}
+
+// backoff 返回第attempt次执行失败之后、下一次重试之前等待的时间，attempt从1开始
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	d := rp.Backoff
+	for i := 1; i < attempt && rp.Multiplier > 1; i++ {
+		d = time.Duration(float64(d) * rp.Multiplier)
+		if rp.MaxBackoff > 0 && d >= rp.MaxBackoff {
+			break
+		}
+	}
+	if rp.MaxBackoff > 0 && d > rp.MaxBackoff {
+		d = rp.MaxBackoff
+	}
+	return d
+}
+
+// Result 是SubmitAsyncRetry的结果
+type Result struct {
+	// Value 任务成功时返回的值
+	Value interface{}
+
+	// Err 最后一次执行的错误（发生panic的时候是*PanicError），或者重新提交失败的错误，成功的时候为nil
+	Err error
+
+	// Attempts 执行的次数
+	Attempts int
+}
+
+// SubmitAsyncRetry 提交一个有返回值的任务，任务返回错误或者发生panic的时候按policy等待之后重新提交到pool中，
+// 返回的channel带有一个缓冲，在任务成功或者次数用完（或者重新提交失败）的时候收到唯一一个Result，之后不会关闭。
+// 等待重试的时候不占用worker；发生的panic仍然会计入Panics()，但不会再交给PanicHandler
+func (p *Pool) SubmitAsyncRetry(task func() (interface{}, error), policy RetryPolicy) <-chan Result {
+	results := make(chan Result, 1)
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	var attempt func(n int)
+	attempt = func(n int) {
+		value, err := p.callRetryable(task)
+		if err == nil || n >= maxAttempts {
+			results <- Result{Value: value, Err: err, Attempts: n}
+			return
+		}
+		time.AfterFunc(policy.backoff(n), func() {
+			if err := p.Submit(func() { attempt(n + 1) }); err != nil {
+				results <- Result{Err: err, Attempts: n}
+			}
+		})
+	}
+	if err := p.Submit(func() { attempt(1) }); err != nil {
+		results <- Result{Err: err}
+	}
+	return results
+}
+
+// callRetryable 执行一次task，把panic转换成*PanicError
+func (p *Pool) callRetryable(task func() (interface{}, error)) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.recordPanic(r)
+			value, err = nil, &PanicError{Value: r}
+		}
+	}()
+	return task()
+}
@@ -0,0 +1,70 @@
+package ants
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitAsyncRetry(t *testing.T) {
+	// 每次重试都要一个新的worker，容量要足够大
+	p, err := NewPool(20)
+	assert.NoError(t, err)
+	defer p.Release()
+
+	errTemporary := errors.New("temporary")
+	var calls int32
+	results := p.SubmitAsyncRetry(func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errTemporary
+		}
+		return "ok", nil
+	}, RetryPolicy{MaxAttempts: 5, Backoff: 5 * time.Millisecond})
+	assert.Equal(t, 1, cap(results))
+	res := <-results
+	assert.NoError(t, res.Err)
+	assert.Equal(t, "ok", res.Value)
+	assert.Equal(t, 3, res.Attempts)
+
+	// 次数用完
+	start := time.Now()
+	res = <-p.SubmitAsyncRetry(func() (interface{}, error) {
+		return nil, errTemporary
+	}, RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond, Multiplier: 2})
+	assert.Equal(t, errTemporary, res.Err)
+	assert.Equal(t, 3, res.Attempts)
+	assert.True(t, time.Since(start) >= 30*time.Millisecond, "should back off 10ms and then 20ms")
+
+	// panic也会重试
+	var panics int32
+	res = <-p.SubmitAsyncRetry(func() (interface{}, error) {
+		if atomic.AddInt32(&panics, 1) == 1 {
+			panic("boom")
+		}
+		return 1, nil
+	}, RetryPolicy{MaxAttempts: 2})
+	assert.NoError(t, res.Err)
+	assert.Equal(t, 2, res.Attempts)
+	res = <-p.SubmitAsyncRetry(func() (interface{}, error) { panic("boom") }, RetryPolicy{})
+	var pe *PanicError
+	assert.True(t, errors.As(res.Err, &pe))
+	assert.Equal(t, 1, res.Attempts)
+
+	p.Release()
+	res = <-p.SubmitAsyncRetry(func() (interface{}, error) { return nil, nil }, RetryPolicy{})
+	assert.Equal(t, ErrPoolClosed, res.Err)
+	assert.Equal(t, 0, res.Attempts)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	rp := RetryPolicy{Backoff: 10 * time.Millisecond, Multiplier: 2, MaxBackoff: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, rp.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, rp.backoff(2))
+	assert.Equal(t, 40*time.Millisecond, rp.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, rp.backoff(4))
+	assert.Equal(t, 50*time.Millisecond, rp.backoff(100))
+	assert.Equal(t, 10*time.Millisecond, RetryPolicy{Backoff: 10 * time.Millisecond}.backoff(5))
+}
@@ -0,0 +1,46 @@
+package ants
+
+// DefaultRouteClass 是Router中默认pool的类别，分类器返回的类别没有对应的pool的时候交给它，没有默认pool的时候返回ErrUnknownClass
+const DefaultRouteClass = ""
+
+// Router 用分类器决定每个任务交给哪个pool执行，比如cpu密集的任务和io密集的任务交给容量不同的pool，
+// 调用者只需要一个提交的入口
+type Router struct {
+	classify func(task func()) string
+	pools    map[string]*Pool
+}
+
+// NewRouter 创建一个Router，classify返回任务的类别，pools是每个类别对应的pool，key为DefaultRouteClass的是默认pool。
+// Router不拥有这些pool，不会关闭它们
+func NewRouter(classify func(task func()) string, pools map[string]*Pool) *Router {
+	r := &Router{
+		classify: classify,
+		pools:    make(map[string]*Pool, len(pools)),
+	}
+	for class, p := range pools {
+		r.pools[class] = p
+	}
+	return r
+}
+
+// Submit 对task分类并提交到对应的pool中，返回那个pool的Submit的错误
+func (r *Router) Submit(task func()) error {
+	p := r.Route(task)
+	if p == nil {
+		return ErrUnknownClass
+	}
+	return p.Submit(task)
+}
+
+// Route 返回task会被提交到的pool，类别未知并且没有默认pool的时候返回nil
+func (r *Router) Route(task func()) *Pool {
+	if p, ok := r.pools[r.classify(task)]; ok {
+		return p
+	}
+	return r.pools[DefaultRouteClass]
+}
+
+// Pool 返回类别对应的pool，不存在的时候返回nil
+func (r *Router) Pool(class string) *Pool {
+	return r.pools[class]
+}
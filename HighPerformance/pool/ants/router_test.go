@@ -0,0 +1,68 @@
+package ants
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var routerWG sync.WaitGroup
+
+func routerCPUTask() { routerWG.Done() }
+
+func routerIOTask() { routerWG.Done() }
+
+func routerOtherTask() { routerWG.Done() }
+
+// classifyByName 按函数名分类
+func classifyByName(task func()) string {
+	name := runtime.FuncForPC(reflect.ValueOf(task).Pointer()).Name()
+	switch {
+	case strings.HasSuffix(name, "routerCPUTask"):
+		return "cpu"
+	case strings.HasSuffix(name, "routerIOTask"):
+		return "io"
+	}
+	return "other"
+}
+
+func TestRouter(t *testing.T) {
+	cpu, err := NewPool(2)
+	assert.NoError(t, err)
+	defer cpu.Release()
+	io, err := NewPool(10)
+	assert.NoError(t, err)
+	defer io.Release()
+
+	r := NewRouter(classifyByName, map[string]*Pool{"cpu": cpu, "io": io})
+	assert.Equal(t, cpu, r.Route(routerCPUTask))
+	assert.Equal(t, io, r.Route(routerIOTask))
+	assert.Equal(t, io, r.Pool("io"))
+
+	routerWG.Add(5)
+	assert.NoError(t, r.Submit(routerCPUTask))
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, r.Submit(routerIOTask))
+	}
+	routerWG.Wait()
+	assert.Equal(t, 1, cpu.Running(), "cpu task should land in the cpu pool")
+	assert.Equal(t, 4, io.Running(), "io tasks should land in the io pool")
+
+	// 未知的类别
+	assert.Nil(t, r.Route(routerOtherTask))
+	assert.Equal(t, ErrUnknownClass, r.Submit(routerOtherTask))
+
+	// 交给默认pool
+	def, err := NewPool(10)
+	assert.NoError(t, err)
+	defer def.Release()
+	r = NewRouter(classifyByName, map[string]*Pool{"cpu": cpu, DefaultRouteClass: def})
+	routerWG.Add(1)
+	assert.NoError(t, r.Submit(routerOtherTask))
+	routerWG.Wait()
+	assert.Equal(t, 1, def.Running())
+}
@@ -0,0 +1,40 @@
+package ants
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// SelfTest 在pool.lock内检查空闲worker的容器，返回发现的异常，没有异常的时候返回nil。会检查：
+// 容器中没有nil的worker，worker的recycleTime从早到晚排列（过期清理依赖这个顺序），
+// 空闲的worker数不超过Running()（Running()包含空闲的worker）
+func (p *Pool) SelfTest() []string {
+	var anomalies []string
+	p.lock.Lock()
+	var (
+		i, idle int
+		last    int64
+	)
+	p.workers.Each(func(w *goWorker) {
+		defer func() { i++ }()
+		if w == nil {
+			anomalies = append(anomalies, fmt.Sprintf("nil worker at index %d", i))
+			return
+		}
+		idle++
+		if w.recycleTime < last {
+			anomalies = append(anomalies, fmt.Sprintf("recycleTime of worker at index %d goes backwards", i))
+		}
+		last = w.recycleTime
+	})
+	if atomic.LoadPointer(&p.hotWorker) != nil {
+		idle++
+	}
+	running := p.Running()
+	p.lock.Unlock()
+
+	if idle > running {
+		anomalies = append(anomalies, fmt.Sprintf("%d idle workers but only %d running", idle, running))
+	}
+	return anomalies
+}
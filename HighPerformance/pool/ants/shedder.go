@@ -0,0 +1,53 @@
+package ants
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// LoadShedder 决定负载过高的时候是否丢弃新的任务，currentLoad是执行任务的worker数和容量的比值，在0到1之间
+type LoadShedder interface {
+	ShouldDrop(currentLoad float64) bool
+}
+
+// probabilisticShedder 负载超过threshold之后，按(load - threshold)/(1 - threshold)的概率丢弃任务
+type probabilisticShedder struct {
+	threshold float64
+}
+
+// NewProbabilisticShedder 返回默认的丢弃策略：负载不超过threshold的时候不丢弃，超过之后丢弃的概率随负载线性增加，
+// 负载达到1的时候全部丢弃
+func NewProbabilisticShedder(threshold float64) LoadShedder {
+	return &probabilisticShedder{threshold: threshold}
+}
+
+func (s *probabilisticShedder) ShouldDrop(currentLoad float64) bool {
+	if currentLoad <= s.threshold {
+		return false
+	}
+	if s.threshold >= 1 {
+		return true
+	}
+	return rand.Float64() < (currentLoad-s.threshold)/(1-s.threshold)
+}
+
+// shed 询问LoadShedder是否丢弃当前提交的任务，丢弃的时候计数并返回true
+func (p *Pool) shed() bool {
+	shedder := p.options.LoadShedder
+	if shedder == nil {
+		return false
+	}
+	capacity := p.Cap()
+	if capacity <= 0 {
+		return false
+	}
+	load := float64(atomic.LoadInt32(&p.busy)) / float64(capacity)
+	if load > 1 {
+		load = 1
+	}
+	if !shedder.ShouldDrop(load) {
+		return false
+	}
+	atomic.AddUint64(&p.droppedTasks, 1)
+	return true
+}
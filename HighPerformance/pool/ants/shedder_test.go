@@ -0,0 +1,85 @@
+package ants
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbabilisticShedder(t *testing.T) {
+	s := NewProbabilisticShedder(0.9)
+	var dropped int
+	for i := 0; i < 1000; i++ {
+		assert.False(t, s.ShouldDrop(0.5), "load under the threshold should never be dropped")
+		assert.True(t, s.ShouldDrop(1), "full load should always be dropped")
+		if s.ShouldDrop(0.95) {
+			dropped++
+		}
+	}
+	assert.InDelta(t, 500, dropped, 100, "half of the tasks should be dropped halfway above the threshold")
+}
+
+// halfShedder 负载达到一半就丢弃
+type halfShedder struct{}
+
+func (halfShedder) ShouldDrop(load float64) bool {
+	return load >= 0.5
+}
+
+func TestLoadShedder(t *testing.T) {
+	p, err := NewPool(2, WithLoadShedder(halfShedder{}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	defer close(block)
+	assert.NoError(t, p.Submit(func() {
+		close(started)
+		<-block
+	}))
+	<-started
+
+	ran := make(chan struct{}, 1)
+	assert.NoError(t, p.Submit(func() { ran <- struct{}{} }), "dropped task should not return an error")
+	select {
+	case <-ran:
+		t.Fatal("task should be dropped under high load")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.EqualValues(t, 1, p.Stats().DroppedTasks)
+}
+
+func TestLoadShedderAllSubmitPaths(t *testing.T) {
+	p, err := NewPool(2, WithLoadShedder(halfShedder{}), WithPreemptiveQueue(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	defer close(block)
+	assert.NoError(t, p.Submit(func() {
+		close(started)
+		<-block
+	}))
+	<-started
+
+	ran := make(chan struct{}, 8)
+	task := func() { ran <- struct{}{} }
+	_, err = p.SubmitTracked(task)
+	assert.NoError(t, err)
+	for _, err := range p.SubmitBatch([]func(){task, task}) {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, p.SubmitNAtomic([]func(){task, task}))
+	assert.NoError(t, p.SubmitWithPriority(1, task))
+	assert.NoError(t, p.SubmitFromSource("a", task))
+	assert.NoError(t, p.SubmitEnvelope(TaskEnvelope{Task: task}))
+	select {
+	case <-ran:
+		t.Fatal("every submit path should consult the load shedder")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.EqualValues(t, 8, p.Stats().DroppedTasks)
+}
@@ -0,0 +1,157 @@
+package ants
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// sourceScheduler 记录每个来源阻塞的提交者的数量，用平滑加权轮询决定下一个拿到worker的来源
+type sourceScheduler struct {
+	weights map[string]int
+	// blocked 每个来源阻塞的提交者的数量，没有阻塞的来源会被删除
+	blocked map[string]int
+	// current 平滑加权轮询中每个来源当前的权重
+	current map[string]int
+	// next 已经选出来还没有拿到worker的来源
+	next   string
+	picked bool
+}
+
+func newSourceScheduler(weights map[string]int) *sourceScheduler {
+	return &sourceScheduler{
+		weights: weights,
+		blocked: make(map[string]int),
+		current: make(map[string]int),
+	}
+}
+
+func (s *sourceScheduler) weight(source string) int {
+	if w := s.weights[source]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+// enter 来源source的一个提交者开始等待worker
+func (s *sourceScheduler) enter(source string) {
+	s.blocked[source]++
+}
+
+// leave 来源source的一个提交者结束等待，拿到了worker或者放弃了
+func (s *sourceScheduler) leave(source string) {
+	if s.blocked[source]--; s.blocked[source] <= 0 {
+		delete(s.blocked, source)
+		delete(s.current, source)
+	}
+	if s.picked && s.next == source {
+		s.picked = false
+	}
+}
+
+// pick 返回下一个应该拿到worker的来源，在它拿到worker之前多次调用返回的都是同一个来源
+func (s *sourceScheduler) pick() string {
+	if s.picked {
+		return s.next
+	}
+	var (
+		total int
+		best  string
+		found bool
+	)
+	for source := range s.blocked {
+		w := s.weight(source)
+		s.current[source] += w
+		total += w
+		if !found || s.current[source] > s.current[best] {
+			best, found = source, true
+		}
+	}
+	if found {
+		s.current[best] -= total
+	}
+	s.next, s.picked = best, found
+	return best
+}
+
+// SubmitFromSource 提交一个来自sourceID的任务，pool繁忙的时候阻塞在这里的各个来源会按照WithSourceWeights配置的权重轮流拿到worker，
+// 来源之间的公平只对SubmitFromSource生效，直接调用Submit的提交者不参与轮询
+func (p *Pool) SubmitFromSource(sourceID string, task func()) error {
+	if p.options.Synchronous || p.isNonblocking() {
+		return p.Submit(task)
+	}
+	if dropped, err := p.admit(); dropped || err != nil {
+		return err
+	}
+	run, untrack := p.trackPending(task, task)
+	w, err := p.retrieveWorkerFromSource(sourceID)
+	if err != nil {
+		untrack()
+	}
+	if err == ErrPoolOverload {
+		return p.overflow(task)
+	}
+	if err != nil {
+		return err
+	}
+	w.dispatch(run)
+	return nil
+}
+
+// workerAvailable 是否能马上拿到一个worker，需要持有pool.lock
+func (p *Pool) workerAvailable() bool {
+	if p.workers.Len() > 0 || atomic.LoadPointer(&p.hotWorker) != nil {
+		return true
+	}
+	capacity := p.Cap()
+	return capacity == -1 || p.Running() < capacity
+}
+
+// retrieveWorkerFromSource 和retrieveWorker一样获取一个worker，只是有worker空闲出来的时候只有轮到的来源才能拿走它
+func (p *Pool) retrieveWorkerFromSource(source string) (*goWorker, error) {
+	p.lock.Lock()
+	if !p.workerAvailable() && p.options.MaxBlockingTasks != 0 && int(atomic.LoadInt32(&p.blockingNum)) >= p.options.MaxBlockingTasks {
+		p.lock.Unlock()
+		p.markFull()
+		return nil, ErrPoolOverload
+	}
+	// 先增加blockingNum再检查有没有worker，和retrieveWorker一样保证不会错过revertWorker快速路径归还的worker
+	atomic.AddInt32(&p.blockingNum, 1)
+	p.sources.enter(source)
+	for {
+		if p.IsClosed() {
+			atomic.AddInt32(&p.blockingNum, -1)
+			p.sources.leave(source)
+			p.lock.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if p.isNonblocking() && !p.workerAvailable() {
+			// 等待的过程中被SetNonblocking切换成了非阻塞
+			atomic.AddInt32(&p.blockingNum, -1)
+			p.sources.leave(source)
+			p.lock.Unlock()
+			p.markFull()
+			return nil, ErrPoolOverload
+		}
+		if p.workerAvailable() {
+			if p.sources.pick() == source {
+				break
+			}
+			// 轮不到自己，把唤醒传递给其它等待的提交者
+			p.cond.Broadcast()
+		}
+		p.cond.Wait()
+	}
+	atomic.AddInt32(&p.blockingNum, -1)
+	p.sources.leave(source)
+	w := p.detachWorker()
+	p.lock.Unlock()
+	if w == nil {
+		if !p.waitSpawn(context.Background()) {
+			p.markFull()
+			return nil, ErrPoolOverload
+		}
+		w = p.workerCache.Get().(*goWorker)
+		w.run()
+	}
+	return w, nil
+}
@@ -0,0 +1,79 @@
+package ants
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceScheduler(t *testing.T) {
+	s := newSourceScheduler(map[string]int{"a": 1, "b": 3})
+	// 每个来源都有两个提交者在等待，拿到worker的提交者马上又重新开始等待
+	for i := 0; i < 2; i++ {
+		s.enter("a")
+		s.enter("b")
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		source := s.pick()
+		assert.Equal(t, source, s.pick(), "pick should be stable until the source leaves")
+		counts[source]++
+		s.leave(source)
+		s.enter(source)
+	}
+	assert.EqualValues(t, 10, counts["a"])
+	assert.EqualValues(t, 30, counts["b"])
+}
+
+func TestSubmitFromSource(t *testing.T) {
+	p, err := NewPool(1, WithSourceWeights(map[string]int{"light": 1, "heavy": 3}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 让pool一直处于饱和的状态，每一轮手动放一个空闲的worker进去
+	p.incRunning()
+	defer p.decRunning()
+
+	var light, heavy int32
+	var wg sync.WaitGroup
+	produce := func(source string, counter *int32) {
+		defer wg.Done()
+		for {
+			if err := p.SubmitFromSource(source, func() { atomic.AddInt32(counter, 1) }); err != nil {
+				return
+			}
+		}
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go produce("light", &light)
+		go produce("heavy", &heavy)
+	}
+	for atomic.LoadInt32(&p.blockingNum) < 8 {
+		time.Sleep(time.Millisecond)
+	}
+
+	const rounds = 40
+	for i := 0; i < rounds; i++ {
+		w := &goWorker{pool: p, task: make(chan func(), 1), recycleTime: nanotime()}
+		p.lock.Lock()
+		_ = p.workers.Insert(w)
+		p.cond.Signal()
+		p.lock.Unlock()
+		select {
+		case task := <-w.task:
+			task()
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no task dispatched in round %d", i)
+		}
+	}
+	p.Release()
+	wg.Wait()
+
+	assert.EqualValues(t, rounds, light+heavy)
+	assert.InDelta(t, rounds*3/4, heavy, 5, "heavy source should get about 3x the dispatch share")
+	assert.InDelta(t, rounds/4, light, 5)
+}
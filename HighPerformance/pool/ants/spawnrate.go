@@ -0,0 +1,87 @@
+package ants
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// spawnLimiter 限制创建worker goroutine的速率，相邻两次创建之间至少间隔interval
+type spawnLimiter struct {
+	// next 下一次允许创建worker的时间(nanotime)，通过原子操作存取
+	next int64
+
+	interval int64
+}
+
+func newSpawnLimiter(perSecond int) *spawnLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &spawnLimiter{interval: int64(time.Second) / int64(perSecond)}
+}
+
+// reserve 预约一次创建，返回到预约的时间还需要等待多久
+func (l *spawnLimiter) reserve() time.Duration {
+	for {
+		now := nanotime()
+		next := atomic.LoadInt64(&l.next)
+		at := next
+		if at < now {
+			at = now
+		}
+		if atomic.CompareAndSwapInt64(&l.next, next, at+l.interval) {
+			return time.Duration(at - now)
+		}
+	}
+}
+
+// tryReserve 不需要等待的时候预约一次创建，需要等待的话不预约并返回false
+func (l *spawnLimiter) tryReserve() bool {
+	for {
+		now := nanotime()
+		next := atomic.LoadInt64(&l.next)
+		if next > now {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&l.next, next, now+l.interval) {
+			return true
+		}
+	}
+}
+
+// waitSpawn 在创建worker之前调用，设置了SpawnRateLimit的时候等到允许创建为止，返回是否可以创建。
+// 等待期间先占住running的一个名额，避免其他调用者看到还有容量而创建超过容量的worker，调用者也计入blockingNum；
+// 非阻塞的pool不等待，ctx在等待中被取消的时候放弃，这两种情况都返回false
+func (p *Pool) waitSpawn(ctx context.Context) bool {
+	l := p.spawnLimiter
+	if l == nil {
+		return true
+	}
+	if p.isNonblocking() {
+		return l.tryReserve()
+	}
+	wait := l.reserve()
+	if wait <= 0 {
+		return true
+	}
+	p.incRunning()
+	atomic.AddInt32(&p.blockingNum, 1)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var ok bool
+	select {
+	case <-timer.C:
+		ok = true
+	case <-ctx.Done():
+	}
+	atomic.AddInt32(&p.blockingNum, -1)
+	p.decRunning()
+	if !ok {
+		// 占住的名额还给等待者
+		p.lock.Lock()
+		p.cond.Signal()
+		p.lock.Unlock()
+	}
+	return ok
+}
@@ -0,0 +1,65 @@
+package ants
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpawnRateLimit(t *testing.T) {
+	_, err := NewPool(10, WithSpawnRateLimit(-1))
+	assert.EqualError(t, err, ErrInvalidPoolConfig.Error())
+
+	// 冷启动的pool收到一批突发的提交，每秒最多创建20个worker，5个worker的创建至少要分散在200ms里
+	var mu sync.Mutex
+	var starts []time.Time
+	p, err := NewPool(10, WithSpawnRateLimit(20), WithOnWorkerStart(func(uint64) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+	}))
+	assert.NoError(t, err)
+	defer p.Release()
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	begin := time.Now()
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, p.Submit(func() { <-release }))
+		}()
+	}
+	wg.Wait()
+	assert.True(t, time.Since(begin) >= 190*time.Millisecond, "spawns were not throttled: %v", time.Since(begin))
+	assert.True(t, p.Running() <= 5)
+	eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(starts) == 5
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.True(t, starts[4].Sub(starts[0]) >= 150*time.Millisecond)
+	mu.Unlock()
+
+	// 等待创建的时候ctx被取消，放弃提交
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	p.spawnLimiter.reserve()
+	assert.Nil(t, p.retrieveWorker(ctx))
+	assert.EqualValues(t, 0, atomic.LoadInt32(&p.blockingNum))
+	close(release)
+
+	// 非阻塞的pool超出速率的时候直接拒绝
+	np, err := NewPool(10, WithSpawnRateLimit(1), WithNonblocking(true))
+	assert.NoError(t, err)
+	defer np.Release()
+	assert.NoError(t, np.Submit(func() {}))
+	assert.Equal(t, ErrPoolOverload, np.Submit(func() {}))
+	assert.False(t, np.TrySubmitFast(func() {}))
+}
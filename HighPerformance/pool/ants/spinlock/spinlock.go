@@ -0,0 +1,33 @@
+// Copyright 2019 Andy Pan. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package spinlock 提供了ants内部使用的自旋锁，临界区很短的时候比sync.Mutex开销更小
+package spinlock
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SpinLock 实现了sync.Locker接口，零值就是一个没有加锁的自旋锁
+type SpinLock uint32
+
+// Lock 获取锁
+func (sl *SpinLock) Lock() {
+	for !atomic.CompareAndSwapUint32((*uint32)(sl), 0, 1) {
+		// 如果没有获取到锁，就让出执行权
+		runtime.Gosched()
+	}
+}
+
+// Unlock 释放锁
+func (sl *SpinLock) Unlock() {
+	atomic.StoreUint32((*uint32)(sl), 0)
+}
+
+// New 创建一个自旋锁
+func New() sync.Locker {
+	return new(SpinLock)
+}
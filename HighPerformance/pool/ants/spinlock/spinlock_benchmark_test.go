@@ -0,0 +1,32 @@
+package spinlock
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// benchmarkLock 在parallelism*GOMAXPROCS个goroutine中争抢同一把锁，临界区内只做一次自增
+func benchmarkLock(b *testing.B, l sync.Locker, parallelism int) {
+	var n int
+	b.SetParallelism(parallelism)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Lock()
+			n++
+			l.Unlock()
+		}
+	})
+}
+
+func BenchmarkLock(b *testing.B) {
+	for _, parallelism := range []int{1, 4, 16} {
+		b.Run("SpinLock/"+strconv.Itoa(parallelism), func(b *testing.B) {
+			benchmarkLock(b, New(), parallelism)
+		})
+		b.Run("Mutex/"+strconv.Itoa(parallelism), func(b *testing.B) {
+			benchmarkLock(b, new(sync.Mutex), parallelism)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package ants
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// maxPanicStackSize 是LastPanic保存的调用栈的最大字节数
+const maxPanicStackSize = 4096
+
+// panicRecord 是最近一次panic的记录
+type panicRecord struct {
+	value interface{}
+	stack []byte
+	when  time.Time
+}
+
+// PoolStats 是pool某一时刻的统计数据
+type PoolStats struct {
+	// pool的容量，-1代表不限制容量
+	Capacity int `json:"capacity"`
+
+	// 正在运行的goroutine的数量，包括空闲的worker
+	Running int `json:"running"`
+
+	// 还可以创建的goroutine的数量，不限制容量的时候是int的最大值
+	Free int `json:"free"`
+
+	// 空闲的worker的数量
+	Idle int `json:"idle"`
+
+	// 阻塞在Submit上的goroutine的数量
+	Blocking int `json:"blocking"`
+
+	// 阻塞等待超过MaxBlockingDuration而被拒绝的次数
+	BlockingTimeouts uint64 `json:"blockingTimeouts"`
+
+	// 被LoadShedder丢弃的任务的数量
+	DroppedTasks uint64 `json:"droppedTasks"`
+
+	// 超过DeadlineAfter还没有开始执行而被跳过的TaskEnvelope，以及开始执行前ctx已经取消而被跳过的SubmitWithContext任务的数量
+	ExpiredTasks uint64 `json:"expiredTasks"`
+
+	// 因为任务超过MaxTaskDuration而被放弃的worker的数量
+	AbandonedWorkers uint64 `json:"abandonedWorkers"`
+
+	// 开启LockProfiling的时候，累计等待获取pool.lock的时间和获取的次数，没有开启的时候都是0
+	LockWait         time.Duration `json:"lockWait"`
+	LockAcquisitions uint64        `json:"lockAcquisitions"`
+}
+
+// AvgLockWait 返回平均每次获取pool.lock等待的时间
+func (s PoolStats) AvgLockWait() time.Duration {
+	if s.LockAcquisitions == 0 {
+		return 0
+	}
+	return s.LockWait / time.Duration(s.LockAcquisitions)
+}
+
+// Stats 返回pool当前的统计数据
+func (p *Pool) Stats() PoolStats {
+	stats := PoolStats{
+		Capacity:         p.Cap(),
+		Running:          p.Running(),
+		Free:             p.Free(),
+		Idle:             p.LenIdle(),
+		Blocking:         int(atomic.LoadInt32(&p.blockingNum)),
+		BlockingTimeouts: atomic.LoadUint64(&p.blockingTimeouts),
+		DroppedTasks:     atomic.LoadUint64(&p.droppedTasks),
+		ExpiredTasks:     atomic.LoadUint64(&p.expiredTasks),
+		AbandonedWorkers: atomic.LoadUint64(&p.abandonedWorkers),
+	}
+	if l, ok := p.lock.(*profiledLock); ok {
+		stats.LockWait, stats.LockAcquisitions = l.stats()
+	}
+	return stats
+}
+
+// add 把另一个pool的统计数据累加进来，任意一个pool不限制容量的话，合计的容量也不限制
+func (s PoolStats) add(o PoolStats) PoolStats {
+	if s.Capacity == -1 || o.Capacity == -1 {
+		s.Capacity = -1
+		s.Free = maxInt
+	} else {
+		s.Capacity += o.Capacity
+		s.Free += o.Free
+	}
+	s.Running += o.Running
+	s.Idle += o.Idle
+	s.Blocking += o.Blocking
+	s.BlockingTimeouts += o.BlockingTimeouts
+	s.DroppedTasks += o.DroppedTasks
+	s.ExpiredTasks += o.ExpiredTasks
+	s.AbandonedWorkers += o.AbandonedWorkers
+	s.LockWait += o.LockWait
+	s.LockAcquisitions += o.LockAcquisitions
+	return s
+}
+
+// TaskQueueDepth 返回还在空闲worker的任务channel缓冲中等待执行的任务的数量，
+// 这些任务已经提交了，但是还没有计入Running，workerChanCap大于1的时候可以用来调整背压
+func (p *Pool) TaskQueueDepth() int {
+	var depth int
+	p.lock.Lock()
+	p.workers.Each(func(w *goWorker) {
+		depth += len(w.task)
+	})
+	p.lock.Unlock()
+	if w := (*goWorker)(atomic.LoadPointer(&p.hotWorker)); w != nil {
+		depth += len(w.task)
+	}
+	return depth
+}
+
+// Panics 返回pool中的任务发生过的panic的次数
+func (p *Pool) Panics() uint64 {
+	return atomic.LoadUint64(&p.panicCount)
+}
+
+// LastPanic 返回最近一次panic的值、调用栈（最多4KB）和发生的时间，没有发生过panic的时候都是零值
+func (p *Pool) LastPanic() (value interface{}, stack []byte, when time.Time) {
+	if r, ok := p.lastPanic.Load().(*panicRecord); ok {
+		return r.value, r.stack, r.when
+	}
+	return nil, nil, time.Time{}
+}
+
+// recordPanic 在recover之后调用，记录panic并返回当前的调用栈
+func (p *Pool) recordPanic(value interface{}) []byte {
+	buf := make([]byte, maxPanicStackSize)
+	buf = buf[:runtime.Stack(buf, false)]
+	atomic.AddUint64(&p.panicCount, 1)
+	if p.stormGuard != nil {
+		p.stormGuard.record(nanotime())
+	}
+	p.lastPanic.Store(&panicRecord{value: value, stack: buf, when: time.Now()})
+	return buf
+}
+
+// Heartbeat 启动一个goroutine每隔interval调用一次fn(p.Stats())，再次调用会替换掉之前的间隔和回调，
+// Heartbeat(0, nil)取消心跳，pool关闭的时候心跳也会停止，Reboot之后需要重新调用
+func (p *Pool) Heartbeat(interval time.Duration, fn func(PoolStats)) {
+	p.heartbeatLock.Lock()
+	defer p.heartbeatLock.Unlock()
+	if p.heartbeatStop != nil {
+		close(p.heartbeatStop)
+		p.heartbeatStop = nil
+	}
+	if interval <= 0 || fn == nil || p.IsClosed() {
+		return
+	}
+	stop := make(chan struct{})
+	p.heartbeatStop = stop
+	p.every(interval, stop, func(time.Time) {
+		fn(p.Stats())
+	})
+}
+
+// startMetricsSampler 设置了MetricsSink的时候启动采样goroutine，它在当前的ShutdownContext被取消（Release）的时候退出
+func (p *Pool) startMetricsSampler() {
+	interval, sink := p.options.MetricsInterval, p.options.MetricsSink
+	if interval <= 0 || sink == nil {
+		return
+	}
+	p.every(interval, nil, func(time.Time) {
+		sink(p.Stats())
+	})
+}
+
+// every 启动一个goroutine每隔interval调用一次fn，stop被关闭或者当前的ShutdownContext被取消（Release）的时候退出，
+// stop为nil的时候只在pool关闭的时候退出
+func (p *Pool) every(interval time.Duration, stop <-chan struct{}, fn func(now time.Time)) {
+	shutdown := p.ShutdownContext().Done()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-shutdown:
+				return
+			case now := <-ticker.C:
+				fn(now)
+			}
+		}
+	}()
+}
@@ -0,0 +1,185 @@
+package ants
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskQueueDepth(t *testing.T) {
+	for _, preAlloc := range []bool{false, true} {
+		p, err := NewPool(10, WithPreAlloc(preAlloc))
+		assert.NoErrorf(t, err, "create Pool failed: %v", err)
+		assert.EqualValues(t, 0, p.TaskQueueDepth())
+
+		p.lock.Lock()
+		for i := 0; i < 3; i++ {
+			w := &goWorker{pool: p, task: make(chan func(), 2)}
+			for j := 0; j < i; j++ {
+				w.task <- demoFunc
+			}
+			_ = p.workers.Insert(w)
+		}
+		p.lock.Unlock()
+		assert.EqualValues(t, 3, p.TaskQueueDepth(), "should sum up the buffered tasks of idle workers")
+
+		// 清空假的worker，避免Release时阻塞在已经满了的channel上
+		p.lock.Lock()
+		for w := p.workers.Detach(); w != nil; w = p.workers.Detach() {
+		}
+		p.lock.Unlock()
+		p.Release()
+	}
+}
+
+func TestLastPanic(t *testing.T) {
+	var wg sync.WaitGroup
+	p, err := NewPool(10, WithPanicHandler(func(interface{}) { wg.Done() }))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	value, stack, when := p.LastPanic()
+	assert.Nil(t, value)
+	assert.Nil(t, stack)
+	assert.True(t, when.IsZero())
+
+	before := time.Now()
+	for i := 1; i <= 2; i++ {
+		wg.Add(1)
+		v := i
+		_ = p.Submit(func() { panic(v) })
+		wg.Wait()
+		assert.EqualValues(t, i, p.Panics(), "panic counter should be incremented")
+	}
+	value, stack, when = p.LastPanic()
+	assert.Equal(t, 2, value, "last panic value should be recorded")
+	assert.Contains(t, string(stack), "goroutine", "stack should be captured")
+	assert.True(t, len(stack) <= maxPanicStackSize, "stack should be bounded")
+	assert.False(t, when.Before(before))
+}
+
+func TestHeartbeat(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	first := make(chan PoolStats, 100)
+	p.Heartbeat(5*time.Millisecond, func(s PoolStats) { first <- s })
+	s := <-first
+	assert.EqualValues(t, 10, s.Capacity)
+
+	// 替换之后旧的回调不再被调用
+	second := make(chan PoolStats, 100)
+	p.Heartbeat(5*time.Millisecond, func(s PoolStats) { second <- s })
+	<-second
+	time.Sleep(10 * time.Millisecond)
+	for len(first) > 0 {
+		<-first
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 0, len(first), "replaced heartbeat should stop")
+
+	// 取消
+	p.Heartbeat(0, nil)
+	time.Sleep(10 * time.Millisecond)
+	for len(second) > 0 {
+		<-second
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 0, len(second), "cancelled heartbeat should stop")
+
+	// pool关闭的时候停止
+	third := make(chan PoolStats, 100)
+	p.Heartbeat(5*time.Millisecond, func(s PoolStats) { third <- s })
+	<-third
+	p.Release()
+	time.Sleep(10 * time.Millisecond)
+	for len(third) > 0 {
+		<-third
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 0, len(third), "heartbeat should stop when the pool is released")
+}
+
+func TestMetricsSampler(t *testing.T) {
+	samples := make(chan PoolStats, 100)
+	p, err := NewPool(10, WithMetricsSampler(5*time.Millisecond, func(s PoolStats) {
+		samples <- s
+	}))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	for i := 0; i < 3; i++ {
+		s := <-samples
+		assert.EqualValues(t, 10, s.Capacity)
+	}
+
+	p.Release()
+	time.Sleep(10 * time.Millisecond)
+	for len(samples) > 0 {
+		<-samples
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 0, len(samples), "sampler should stop after Release")
+
+	p.Reboot()
+	select {
+	case <-samples:
+	case <-time.After(time.Second):
+		t.Fatal("sampler should restart after Reboot")
+	}
+}
+
+func TestLockProfiling(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	_, ok := p.lock.(*profiledLock)
+	assert.False(t, ok, "pool.lock should not be wrapped without LockProfiling")
+	p.Release()
+
+	p, err = NewPool(10, WithLockProfiling(true))
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// n个goroutine同时抢锁，每次持有锁1ms
+	contend := func(n int) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.lock.Lock()
+				time.Sleep(time.Millisecond)
+				p.lock.Unlock()
+			}()
+		}
+		wg.Wait()
+	}
+	contend(2)
+	low := p.Stats()
+	assert.True(t, low.LockWait > 0, "lock wait should be recorded under contention")
+	assert.True(t, low.AvgLockWait() > 0)
+
+	contend(20)
+	high := p.Stats()
+	assert.True(t, high.LockAcquisitions > low.LockAcquisitions)
+	assert.True(t, high.LockWait-low.LockWait > low.LockWait, "lock wait should grow with contention: %v -> %v",
+		low.LockWait, high.LockWait)
+}
+
+func TestPoolStatsJSON(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	data, err := json.Marshal(p.Stats())
+	assert.NoError(t, err)
+	// 和PoolConfig、PoolState一样使用camelCase
+	assert.Contains(t, string(data), `"blockingTimeouts":0`)
+	assert.Contains(t, string(data), `"droppedTasks":0`)
+	assert.False(t, strings.Contains(string(data), "_"), string(data))
+}
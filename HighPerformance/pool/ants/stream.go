@@ -0,0 +1,64 @@
+package ants
+
+import (
+	"io"
+	"sync"
+)
+
+// ErrInvalidChunkSize will be returned when ProcessReader is called with a non-positive chunk size.
+var ErrInvalidChunkSize = newPoolError("chunk size must be positive", ErrInvalidArgument)
+
+// ProcessReader 从r中按chunkSize读取数据块，每个数据块提交一个任务到p中调用f，并发数受pool的容量限制，
+// index是数据块在r中的顺序，调用者可以据此还原顺序，最后一个数据块可能比chunkSize小。
+// 数据块的内存会被复用，f返回之后就不能再持有chunk。
+// 所有的数据块处理完或者读取出错的时候返回，出错时也会等待已经提交的任务结束
+func ProcessReader(p *Pool, r io.Reader, chunkSize int, f func(chunk []byte, index int)) error {
+	if chunkSize <= 0 {
+		return ErrInvalidChunkSize
+	}
+	buffers := sync.Pool{New: func() interface{} {
+		buf := make([]byte, chunkSize)
+		return &buf
+	}}
+
+	var (
+		wg  sync.WaitGroup
+		err error
+	)
+	for index := 0; ; index++ {
+		buf := buffers.Get().(*[]byte)
+		n, rErr := io.ReadFull(r, *buf)
+		if n == 0 {
+			buffers.Put(buf)
+			if rErr != io.EOF {
+				err = rErr
+			}
+			break
+		}
+
+		wg.Add(1)
+		i := index
+		if sErr := p.Submit(func() {
+			defer func() {
+				buffers.Put(buf)
+				wg.Done()
+			}()
+			f((*buf)[:n], i)
+		}); sErr != nil {
+			wg.Done()
+			err = sErr
+			break
+		}
+
+		// 读到了最后一个不完整的数据块
+		if rErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rErr != nil {
+			err = rErr
+			break
+		}
+	}
+	wg.Wait()
+	return err
+}
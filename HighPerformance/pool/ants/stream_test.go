@@ -0,0 +1,70 @@
+package ants
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessReader(t *testing.T) {
+	p, err := NewPool(16)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	data := make([]byte, 10*KiB+123)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	chunkSize := KiB
+	chunks := make([][]byte, (len(data)+chunkSize-1)/chunkSize)
+	var lock sync.Mutex
+	err = ProcessReader(p, bytes.NewReader(data), chunkSize, func(chunk []byte, index int) {
+		lock.Lock()
+		defer lock.Unlock()
+		// chunk会被复用，需要拷贝一份
+		chunks[index] = append([]byte(nil), chunk...)
+	})
+	assert.NoErrorf(t, err, "process reader failed: %v", err)
+	for i, chunk := range chunks {
+		assert.NotNilf(t, chunk, "chunk %d should be delivered", i)
+	}
+	assert.Len(t, chunks[len(chunks)-1], 123, "last chunk should be partial")
+	assert.Equal(t, data, bytes.Join(chunks, nil), "chunks should be restored in order by index")
+
+	// 空的reader
+	var called bool
+	assert.NoError(t, ProcessReader(p, bytes.NewReader(nil), chunkSize, func([]byte, int) { called = true }))
+	assert.False(t, called)
+
+	assert.Equal(t, ErrInvalidChunkSize, ProcessReader(p, bytes.NewReader(data), 0, nil))
+}
+
+func TestProcessReaderError(t *testing.T) {
+	p, err := NewPool(16)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	errRead := errors.New("read failed")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 2*KiB)), &errReader{err: errRead})
+	var count int32
+	var lock sync.Mutex
+	err = ProcessReader(p, r, KiB, func([]byte, int) {
+		lock.Lock()
+		count++
+		lock.Unlock()
+	})
+	assert.Equal(t, errRead, err)
+	assert.EqualValues(t, 2, count, "chunks read before the error should be processed")
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
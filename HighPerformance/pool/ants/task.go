@@ -0,0 +1,86 @@
+package ants
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Task 的状态
+const (
+	taskPending int32 = iota
+	taskRunning
+	taskCancelled
+	taskFinished
+)
+
+// Task 是通过SubmitTask提交的任务的句柄，可以取消还没有开始的任务，也可以等待任务的结果
+type Task struct {
+	state  int32
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// SubmitTask 提交一个有返回值的任务并返回它的句柄，提交失败的时候句柄已经结束，Await返回提交的错误。
+// 任务发生panic的时候Await返回包含panic值的*PanicError，panic不会再交给PanicHandler，但仍然会计入Panics()
+func (p *Pool) SubmitTask(task func() (interface{}, error)) *Task {
+	t := &Task{done: make(chan struct{})}
+	if err := p.Submit(func() { t.run(p, task) }); err != nil {
+		if atomic.CompareAndSwapInt32(&t.state, taskPending, taskFinished) {
+			t.finish(nil, err)
+		}
+	}
+	return t
+}
+
+// run 在worker中执行任务，任务已经被取消的时候直接返回
+func (t *Task) run(p *Pool, task func() (interface{}, error)) {
+	if !atomic.CompareAndSwapInt32(&t.state, taskPending, taskRunning) {
+		return
+	}
+	var (
+		result interface{}
+		err    error
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			p.recordPanic(r)
+			result, err = nil, &PanicError{Value: r}
+		}
+		atomic.StoreInt32(&t.state, taskFinished)
+		t.finish(result, err)
+	}()
+	result, err = task()
+}
+
+// finish 记录结果并通知等待者，只会被调用一次
+func (t *Task) finish(result interface{}, err error) {
+	t.result, t.err = result, err
+	close(t.done)
+}
+
+// Cancel 取消还没有开始执行的任务，返回是否取消成功。Cancel和worker开始执行任务是竞争的，
+// 只有一方会成功：Cancel先成功的话任务不会再执行，Await返回ErrTaskCancelled；
+// 任务已经开始执行或者已经结束的时候返回false，任务会正常执行完
+func (t *Task) Cancel() bool {
+	if !atomic.CompareAndSwapInt32(&t.state, taskPending, taskCancelled) {
+		return false
+	}
+	t.finish(nil, ErrTaskCancelled)
+	return true
+}
+
+// Await 等待任务结束并返回它的结果，ctx先结束的时候返回ctx.Err()，任务不会因此被取消
+func (t *Task) Await(ctx context.Context) (interface{}, error) {
+	select {
+	case <-t.done:
+		return t.result, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Done 返回一个在任务结束（完成、取消或者提交失败）时关闭的channel
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
@@ -0,0 +1,86 @@
+package ants
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitTaskAwait(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	task := p.SubmitTask(func() (interface{}, error) { return 42, nil })
+	v, err := task.Await(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	assert.False(t, task.Cancel(), "a finished task can not be cancelled")
+
+	errFailed := errors.New("failed")
+	_, err = p.SubmitTask(func() (interface{}, error) { return nil, errFailed }).Await(context.Background())
+	assert.Equal(t, errFailed, err)
+
+	_, err = p.SubmitTask(func() (interface{}, error) { panic("boom") }).Await(context.Background())
+	assert.EqualError(t, err, "task panicked: boom")
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe), "the panic value should be reachable through errors.As")
+	assert.Equal(t, "boom", pe.Value)
+
+	p.Release()
+	task = p.SubmitTask(func() (interface{}, error) { return nil, nil })
+	<-task.Done()
+	_, err = task.Await(context.Background())
+	assert.Equal(t, ErrPoolClosed, err)
+}
+
+func TestSubmitTaskAwaitCancelledCtx(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	block := make(chan struct{})
+	task := p.SubmitTask(func() (interface{}, error) {
+		<-block
+		return "done", nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = task.Await(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// ctx结束不会取消任务
+	close(block)
+	v, err := task.Await(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "done", v)
+}
+
+func TestSubmitTaskCancelBeforeStart(t *testing.T) {
+	p, err := NewPool(10)
+	assert.NoErrorf(t, err, "create Pool failed: %v", err)
+	defer p.Release()
+
+	// 任务交给一个不会运行的worker，在它开始之前取消
+	w := &goWorker{pool: p, task: make(chan func(), 1)}
+	p.incRunning()
+	defer p.decRunning()
+	assert.True(t, p.revertWorker(w))
+
+	var ran int32
+	task := p.SubmitTask(func() (interface{}, error) {
+		atomic.StoreInt32(&ran, 1)
+		return nil, nil
+	})
+	assert.True(t, task.Cancel())
+	assert.False(t, task.Cancel(), "a task can only be cancelled once")
+	_, err = task.Await(context.Background())
+	assert.Equal(t, ErrTaskCancelled, err)
+
+	(<-w.task)()
+	assert.EqualValues(t, 0, atomic.LoadInt32(&ran), "a cancelled task should not run")
+}
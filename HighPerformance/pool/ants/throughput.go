@@ -0,0 +1,54 @@
+package ants
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// completionLogSize 完成记录环形缓冲的大小，最多只保留最近这么多次完成的时间
+const completionLogSize = 1024
+
+// completionLog 用环形缓冲记录最近completionLogSize次任务完成的时间(nanotime)，写入和读取都是无锁的
+type completionLog struct {
+	// next 下一次写入的位置，只增不减，对completionLogSize取模得到下标
+	next  uint64
+	times [completionLogSize]int64
+}
+
+// record 记录一次发生在now的任务完成
+func (l *completionLog) record(now int64) {
+	i := (atomic.AddUint64(&l.next, 1) - 1) % completionLogSize
+	atomic.StoreInt64(&l.times[i], now)
+}
+
+// rate 计算最近d之内平均每秒完成的任务数，缓冲中的记录都在d之内（完成得太快，缓冲不够覆盖整个d）的时候，
+// 用缓冲中最早的一次到现在的时间计算
+func (l *completionLog) rate(d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	now := nanotime()
+	since := now - int64(d)
+	oldest := now
+	var count int
+	for i := range l.times {
+		t := atomic.LoadInt64(&l.times[i])
+		if t == 0 || t < since {
+			continue
+		}
+		count++
+		if t < oldest {
+			oldest = t
+		}
+	}
+	if count == completionLogSize && now > oldest {
+		return float64(count) / time.Duration(now-oldest).Seconds()
+	}
+	return float64(count) / d.Seconds()
+}
+
+// ThroughputLast 返回最近d之内平均每秒完成的任务数（包括panic的任务），可以用来做自适应的限流，比如吞吐量下降的时候放慢提交。
+// 只保留最近1024次完成的时间，d之内完成的任务超过这个数量的时候按这些记录覆盖的时间估算
+func (p *Pool) ThroughputLast(d time.Duration) float64 {
+	return p.completions.rate(d)
+}
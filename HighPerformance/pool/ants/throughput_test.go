@@ -0,0 +1,43 @@
+package ants
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThroughputLast(t *testing.T) {
+	p, err := NewPool(20)
+	assert.NoError(t, err)
+	defer p.Release()
+	assert.Zero(t, p.ThroughputLast(time.Second))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		assert.NoError(t, p.Submit(wg.Done))
+	}
+	wg.Wait()
+	eventually(t, func() bool {
+		return p.ThroughputLast(time.Second) == 10
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 5.0, p.ThroughputLast(2*time.Second))
+	assert.Zero(t, p.ThroughputLast(0))
+
+	// 超出窗口的完成不再计入
+	time.Sleep(60 * time.Millisecond)
+	assert.Zero(t, p.ThroughputLast(50*time.Millisecond))
+}
+
+func TestCompletionLogBounded(t *testing.T) {
+	var l completionLog
+	now := nanotime()
+	// 1s之内完成了两倍于缓冲大小的任务，只保留最近的completionLogSize次，按它们覆盖的时间估算
+	for i := 0; i < 2*completionLogSize; i++ {
+		l.record(now - int64(2*completionLogSize-i)*int64(time.Millisecond)/4)
+	}
+	rate := l.rate(time.Second)
+	assert.InDelta(t, 4000, rate, 100)
+}
@@ -0,0 +1,49 @@
+package ants
+
+import (
+	"sync"
+
+	"github.com/panjf2000/ants/v2/internal"
+)
+
+// taskValues 保存每个正在运行的任务的值和标签，key为运行任务的goroutine的id，value为*taskLocals
+var taskValues sync.Map
+
+// taskLocals 是一个正在运行的任务的值（SubmitWithValues）和标签（SubmitEnvelope）
+type taskLocals struct {
+	values map[string]interface{}
+	labels map[string]string
+}
+
+// runWithLocals 在task运行期间把locals登记到taskValues中，任务结束后清理
+func runWithLocals(locals *taskLocals, task func()) {
+	id := internal.GoID()
+	taskValues.Store(id, locals)
+	defer taskValues.Delete(id)
+	task()
+}
+
+// currentLocals 返回当前goroutine上正在运行的任务的taskLocals，不在这样的任务中调用时返回nil
+func currentLocals() *taskLocals {
+	v, ok := taskValues.Load(internal.GoID())
+	if !ok {
+		return nil
+	}
+	return v.(*taskLocals)
+}
+
+// SubmitWithValues 提交一个任务，kv在任务运行期间可以通过FromTask读取，任务结束后会被清理
+func (p *Pool) SubmitWithValues(kv map[string]interface{}, task func()) error {
+	return p.Submit(func() {
+		runWithLocals(&taskLocals{values: kv}, task)
+	})
+}
+
+// FromTask 在通过SubmitWithValues提交的任务中读取key对应的值，不在这样的任务中调用时返回nil
+func FromTask(key string) interface{} {
+	locals := currentLocals()
+	if locals == nil {
+		return nil
+	}
+	return locals.values[key]
+}
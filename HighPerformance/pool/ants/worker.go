@@ -23,7 +23,9 @@
 package ants
 
 import (
+	"context"
 	"runtime"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,44 +33,137 @@ import (
 type goWorker struct {
 	pool        *Pool       // 拥有当前worker的指针
 	task        chan func() // 需要被执行的任务
-	recycleTime time.Time   // 回收时的​时间
+	recycleTime int64       // 回收时的单调时钟时间，见nanotime
+	id          uint64      // 每次启动goroutine的时候分配的id，单调递增
+
+	// 以下字段只在worker自己的goroutine中访问
+	goid  uint64          // worker的goroutine的id，注册到taskWorkers之后才不为0
+	ctx   context.Context // 当前任务的基础context
+	local interface{}     // WorkerLocal返回的对象，第一次调用的时候创建
+}
+
+// Worker 是WorkerArray中存放的空闲worker，字段都不公开，自定义的WorkerArray只能通过RecycleTime和Stop使用它
+type Worker = goWorker
+
+// RecycleTime 返回worker最近一次归还到pool的时间，RetrieveExpiry根据它判断worker是否过期
+func (w *goWorker) RecycleTime() time.Time {
+	return monotonicBase.Add(time.Duration(w.recycleTime))
+}
+
+// Stop 通知worker的goroutine退出，只能对从WorkerArray中取出或者Reset时的空闲worker调用
+func (w *goWorker) Stop() {
+	w.task <- nil
 }
 
 // run 开启了一个goroutine执行指定的方法来处理任务
 func (w *goWorker) run() {
 	// 增加运行的goroutine数量
 	w.pool.incRunning()
+	atomic.AddInt32(&w.pool.alive, 1)
+	w.id = atomic.AddUint64(&w.pool.workerSeq, 1)
+	// w放回workerCache之后可能被重新使用，退出时的回调使用这里记下的id
+	id := w.id
 	go func() {
+		if cpus := w.pool.options.CPUAffinity; len(cpus) > 0 {
+			pinWorker(cpus, &w.pool.cpuCursor, w.pool.options.Logger)
+		} else if w.pool.options.OSThreads {
+			// 比下面的defer先注册，在worker的清理工作都做完之后才释放线程
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+		}
+		w.pool.callWorkerHook(w.pool.options.OnWorkerStart, id)
+		// start 当前任务开始执行的时间，只在设置了OnTaskDone的时候记录
+		var start time.Time
+		// busy 是否正在执行任务
+		var busy bool
+		// state 设置了MaxTaskDuration的时候当前任务的状态，和超时的定时器竞争，见watchTask
+		var state *int32
+		var timer *time.Timer
 		// 在任务处理完成后，
 		defer func() {
-			w.pool.decRunning()
+			// worker已经被放弃的话，pool中的计数在放弃的时候就已经处理过了
+			var abandoned bool
+			if state != nil {
+				timer.Stop()
+				atomic.CompareAndSwapInt32(state, workerTaskRunning, workerTaskDone)
+				abandoned = atomic.LoadInt32(state) == workerTaskAbandoned
+			}
+			// 任务发生了panic，被放弃的worker上的任务也一样
+			if busy && w.pool.options.TaskTracking {
+				w.pool.endTask(id)
+			}
+			if !abandoned {
+				if busy {
+					w.pool.completions.record(nanotime())
+					w.pool.taskFinished()
+				}
+				w.pool.decRunning()
+				w.pool.markNotFull()
+			}
+			w.clearContext()
 			// 将worker归还到workerCache中
 			w.pool.workerCache.Put(w)
 			//处理异常
 			if p := recover(); p != nil {
+				// 记录panic，同时获取此时的运行栈
+				stack := w.pool.recordPanic(p)
+				if onTaskDone := w.pool.options.OnTaskDone; onTaskDone != nil {
+					onTaskDone(time.Since(start), p)
+				}
 				// 使用定制的PanicHandler
 				if ph := w.pool.options.PanicHandler; ph != nil {
 					ph(p)
 				} else {
 					w.pool.options.Logger.Printf("worker exits from a panic: %v\n", p)
-					var buf [4096]byte
-					// 获取此时的运行栈
-					n := runtime.Stack(buf[:], false)
-					w.pool.options.Logger.Printf("worker exits from panic: %s\n", string(buf[:n]))
+					w.pool.options.Logger.Printf("worker exits from panic: %s\n", string(stack))
 				}
 			}
 			// 没有发生panic：
 			// 调用 Signal()通知那些等待获取可用goroutine的被阻塞的调用者
 			// here in case there are goroutines waiting for available workers.
 			w.pool.cond.Signal()
+			w.pool.notifyCapacity()
+			w.pool.callWorkerHook(w.pool.options.OnWorkerStop, id)
+			if !abandoned {
+				atomic.AddInt32(&w.pool.alive, -1)
+			}
 		}()
 
 		for f := range w.task {
 			if f == nil {
 				return
 			}
+			w.injectContext()
+			onTaskDone := w.pool.options.OnTaskDone
+			if onTaskDone != nil {
+				start = time.Now()
+			}
+			busy = true
+			w.pool.taskStarted()
+			if d := w.pool.options.MaxTaskDuration; d > 0 {
+				state, timer = w.pool.watchTask(d)
+			}
+			if w.pool.options.TaskTracking {
+				w.pool.beginTask(id)
+			}
 			// 执行每一个任务
 			f()
+			if w.pool.options.TaskTracking {
+				w.pool.endTask(id)
+			}
+			if state != nil {
+				timer.Stop()
+				// 超时的时候worker已经被放弃，直接退出
+				if !atomic.CompareAndSwapInt32(state, workerTaskRunning, workerTaskDone) {
+					return
+				}
+			}
+			if onTaskDone != nil {
+				onTaskDone(time.Since(start), nil)
+			}
+			busy = false
+			w.pool.completions.record(nanotime())
+			w.pool.taskFinished()
 			time.Sleep(10 * time.Second)
 			// 执行完，将worker归还到pool中
 			if ok := w.pool.revertWorker(w); !ok {
@@ -77,3 +172,22 @@ func (w *goWorker) run() {
 		}
 	}()
 }
+
+// callWorkerHook 调用OnWorkerStart或者OnWorkerStop，回调中的panic只记录日志
+func (p *Pool) callWorkerHook(hook func(workerID uint64), id uint64) {
+	if hook == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			p.options.Logger.Printf("worker %d lifecycle callback panicked: %v\n", id, r)
+		}
+	}()
+	hook(id)
+}
+
+// dispatch 把任务交给worker，任务执行完之前计入InFlight
+func (w *goWorker) dispatch(task func()) {
+	atomic.AddInt32(&w.pool.inFlight, 1)
+	w.task <- task
+}
@@ -11,35 +11,44 @@ var (
 
 	// 向已经释放的worker queue中插入元素的时候
 	errQueueIsReleased = errors.New("the queue length is zero")
+
+	// 缩小后的容量放不下队列中已有的元素
+	errQueueShrinkTooSmall = errors.New("the new size is smaller than the number of items in the queue")
 )
 
-type workerArray interface {
+// WorkerArray 是存放空闲worker的容器，可以通过Pool.SwapWorkerArray在运行时替换。
+// 除了Len和IsEmpty之外，所有方法都在持有pool.lock的时候调用，实现不需要自己加锁
+type WorkerArray interface {
 	//pool中元素的个数
-	len() int
+	Len() int
 	//pool是否为空
-	isEmpty() bool
-	//插入一个goWorker的指针到pool中
-	insert(worker *goWorker) error
-	//取出一个任务
-	detach() *goWorker
-	//取回过期
-	retrieveExpiry(duration time.Duration) []*goWorker
-	//重置整个pool
-	reset()
+	IsEmpty() bool
+	//插入一个Worker的指针到pool中
+	Insert(worker *Worker) error
+	//取出一个Worker，为空的时候返回nil
+	Detach() *Worker
+	//取出所有RecycleTime早于duration之前的worker
+	RetrieveExpiry(duration time.Duration) []*Worker
+	//停止并清空所有的worker
+	Reset()
+	//按顺序遍历所有的worker
+	Each(fn func(w *Worker))
 }
 
-type arrayType int
+// ArrayType 是WorkerArray的实现类型
+type ArrayType int
 
 const (
-	stackType     arrayType = 1 << iota //栈
-	loopQueueType                       //队列
+	StackType     ArrayType = 1 << iota //栈
+	LoopQueueType                       //队列
 )
 
-func newWorkerArray(aType arrayType, size int) workerArray {
+// NewWorkerArray 创建一个指定类型的WorkerArray，size为预先分配的容量，对于LoopQueueType来说也是它能存放的最大worker数量
+func NewWorkerArray(aType ArrayType, size int) WorkerArray {
 	switch aType {
-	case stackType:
+	case StackType:
 		return newWorkerStack(size)
-	case loopQueueType:
+	case LoopQueueType:
 		return newWorkerLoopQueue(size)
 	default:
 		return newWorkerStack(size)
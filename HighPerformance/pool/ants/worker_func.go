@@ -30,16 +30,28 @@ import (
 type goWorkerWithFunc struct {
 	pool        *PoolWithFunc    // 拥有当前worker的pool
 	args        chan interface{} // args 是需要执行的job
-	recycleTime time.Time        // recycleTime 当worker归还到队列中时更新此事件
+	recycleTime int64            // recycleTime 当worker归还到队列中时更新此时间，单位是nanotime返回的纳秒数
 }
 
 func (w *goWorkerWithFunc) run() {
 	w.pool.incRunning()
 	go func() {
+		if cpus := w.pool.options.CPUAffinity; len(cpus) > 0 {
+			pinWorker(cpus, &w.pool.cpuCursor, w.pool.options.Logger)
+		} else if w.pool.options.OSThreads {
+			// 比下面的defer先注册，在worker的清理工作都做完之后才释放线程
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+		}
+		// start 当前任务开始执行的时间，只在设置了OnTaskDone的时候记录
+		var start time.Time
 		defer func() {
 			w.pool.decRunning()
 			w.pool.workerCache.Put(w)
 			if p := recover(); p != nil {
+				if onTaskDone := w.pool.options.OnTaskDone; onTaskDone != nil {
+					onTaskDone(time.Since(start), p)
+				}
 				if ph := w.pool.options.PanicHandler; ph != nil {
 					ph(p)
 				} else {
@@ -58,8 +70,15 @@ func (w *goWorkerWithFunc) run() {
 			if args == nil {
 				return
 			}
+			onTaskDone := w.pool.options.OnTaskDone
+			if onTaskDone != nil {
+				start = time.Now()
+			}
 			// 通过指定的方法处理job
 			w.pool.poolFunc(args)
+			if onTaskDone != nil {
+				onTaskDone(time.Since(start), nil)
+			}
 			// 归还
 			if ok := w.pool.revertWorker(w); !ok {
 				return
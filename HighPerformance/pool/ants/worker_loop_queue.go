@@ -19,7 +19,7 @@ func newWorkerLoopQueue(size int) *loopQueue {
 	}
 }
 
-func (wq *loopQueue) len() int {
+func (wq *loopQueue) Len() int {
 	if wq.size == 0 {
 		return 0
 	}
@@ -38,11 +38,11 @@ func (wq *loopQueue) len() int {
 	return wq.size - wq.head + wq.tail
 }
 
-func (wq *loopQueue) isEmpty() bool {
+func (wq *loopQueue) IsEmpty() bool {
 	return wq.head == wq.tail && !wq.isFull
 }
 
-func (wq *loopQueue) insert(worker *goWorker) error {
+func (wq *loopQueue) Insert(worker *goWorker) error {
 	// 当被释放后，环形队列的容量为0
 	if wq.size == 0 {
 		return errQueueIsReleased
@@ -67,8 +67,8 @@ func (wq *loopQueue) insert(worker *goWorker) error {
 }
 
 // 从环形队列的第一个位置取出任务
-func (wq *loopQueue) detach() *goWorker {
-	if wq.isEmpty() {
+func (wq *loopQueue) Detach() *goWorker {
+	if wq.IsEmpty() {
 		return nil
 	}
 
@@ -86,18 +86,18 @@ func (wq *loopQueue) detach() *goWorker {
 }
 
 // 回收过期任务
-func (wq *loopQueue) retrieveExpiry(duration time.Duration) []*goWorker {
-	if wq.isEmpty() {
+func (wq *loopQueue) RetrieveExpiry(duration time.Duration) []*goWorker {
+	if wq.IsEmpty() {
 		return nil
 	}
 	// 清空过期队列
 	wq.expiry = wq.expiry[:0]
 	// 过期时间
-	expiryTime := time.Now().Add(-duration)
+	expiryTime := nanotime() - int64(duration)
 	// 环形队列不为空
-	for !wq.isEmpty() {
+	for !wq.IsEmpty() {
 		// 此任务的recycleTime
-		if expiryTime.Before(wq.items[wq.head].recycleTime) {
+		if expiryTime < wq.items[wq.head].recycleTime {
 			break
 		}
 		// 加入到过期队列中
@@ -114,13 +114,45 @@ func (wq *loopQueue) retrieveExpiry(duration time.Duration) []*goWorker {
 	return wq.expiry
 }
 
-func (wq *loopQueue) reset() {
-	if wq.isEmpty() {
+// shrink 把环形队列的容量缩小到newSize，释放多余的内存，队列中的worker会按原来的顺序保留下来，
+// newSize不能小于当前worker的数量，不小于当前容量的时候什么也不做
+func (wq *loopQueue) shrink(newSize int) error {
+	n := wq.Len()
+	if newSize <= 0 || newSize < n {
+		return errQueueShrinkTooSmall
+	}
+	if newSize >= wq.size {
+		return nil
+	}
+
+	items := make([]*goWorker, newSize)
+	for i := 0; i < n; i++ {
+		items[i] = wq.items[(wq.head+i)%wq.size]
+	}
+	wq.items = items
+	wq.size = newSize
+	wq.head = 0
+	// 新的队列中元素从头部开始连续存放
+	wq.tail = n % newSize
+	wq.isFull = n == newSize
+
+	return nil
+}
+
+// 从head开始遍历队列中的worker
+func (wq *loopQueue) Each(fn func(w *goWorker)) {
+	for i, n := 0, wq.Len(); i < n; i++ {
+		fn(wq.items[(wq.head+i)%wq.size])
+	}
+}
+
+func (wq *loopQueue) Reset() {
+	if wq.IsEmpty() {
 		return
 	}
 
 Releasing:
-	if w := wq.detach(); w != nil {
+	if w := wq.Detach(); w != nil {
 		// 逐个将队列中的任务都设置为nil
 		w.task <- nil
 		goto Releasing
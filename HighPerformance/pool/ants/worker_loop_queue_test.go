@@ -10,9 +10,9 @@ import (
 func TestNewLoopQueue(t *testing.T) {
 	size := 100
 	q := newWorkerLoopQueue(size)
-	assert.EqualValues(t, 0, q.len(), "Len error")
-	assert.Equal(t, true, q.isEmpty(), "IsEmpty error")
-	assert.Nil(t, q.detach(), "Dequeue error")
+	assert.EqualValues(t, 0, q.Len(), "Len error")
+	assert.Equal(t, true, q.IsEmpty(), "IsEmpty error")
+	assert.Nil(t, q.Detach(), "Dequeue error")
 }
 
 func TestLoopQueue(t *testing.T) {
@@ -20,29 +20,79 @@ func TestLoopQueue(t *testing.T) {
 	q := newWorkerLoopQueue(size)
 
 	for i := 0; i < 5; i++ {
-		err := q.insert(&goWorker{recycleTime: time.Now()})
+		err := q.Insert(&goWorker{recycleTime: nanotime()})
 		if err != nil {
 			break
 		}
 	}
-	assert.EqualValues(t, 5, q.len(), "Len error")
-	v := q.detach()
+	assert.EqualValues(t, 5, q.Len(), "Len error")
+	v := q.Detach()
 	t.Log(v)
-	assert.EqualValues(t, 4, q.len(), "Len error")
+	assert.EqualValues(t, 4, q.Len(), "Len error")
 
 	time.Sleep(time.Second)
 
 	for i := 0; i < 6; i++ {
-		err := q.insert(&goWorker{recycleTime: time.Now()})
+		err := q.Insert(&goWorker{recycleTime: nanotime()})
 		if err != nil {
 			break
 		}
 	}
-	assert.EqualValues(t, 10, q.len(), "Len error")
+	assert.EqualValues(t, 10, q.Len(), "Len error")
 
-	err := q.insert(&goWorker{recycleTime: time.Now()})
+	err := q.Insert(&goWorker{recycleTime: nanotime()})
 	assert.Error(t, err, "Enqueue, error")
 
-	q.retrieveExpiry(time.Second)
-	assert.EqualValuesf(t, 6, q.len(), "Len error: %d", q.len())
+	q.RetrieveExpiry(time.Second)
+	assert.EqualValuesf(t, 6, q.Len(), "Len error: %d", q.Len())
+}
+
+func TestLoopQueueShrink(t *testing.T) {
+	size := 10
+	q := newWorkerLoopQueue(size)
+
+	workers := make([]*goWorker, 8)
+	for i := range workers {
+		workers[i] = &goWorker{recycleTime: nanotime()}
+		assert.NoError(t, q.Insert(workers[i]), "Enqueue error")
+	}
+	// 让head和tail绕过环的尾部
+	for i := 0; i < 5; i++ {
+		q.Detach()
+	}
+	for i := 0; i < 4; i++ {
+		w := &goWorker{recycleTime: nanotime()}
+		workers = append(workers, w)
+		assert.NoError(t, q.Insert(w), "Enqueue error")
+	}
+	assert.EqualValues(t, 7, q.Len(), "Len error")
+
+	assert.Error(t, q.shrink(6), "shrink below the number of items should fail")
+	assert.NoError(t, q.shrink(8), "Shrink error")
+	assert.EqualValues(t, 8, q.size, "Size error")
+	assert.EqualValues(t, 7, q.Len(), "Len error")
+
+	assert.NoError(t, q.Insert(&goWorker{recycleTime: nanotime()}), "Enqueue error")
+	assert.Error(t, q.Insert(&goWorker{recycleTime: nanotime()}), "queue should be full after shrinking")
+	for i := 5; i < len(workers); i++ {
+		assert.Equal(t, workers[i], q.Detach(), "shrink should keep the order of items")
+	}
+
+	assert.NoError(t, q.shrink(20), "growing should be a no-op")
+	assert.EqualValues(t, 8, q.size, "Size error")
+}
+
+func TestRetrieveExpiryMonotonic(t *testing.T) {
+	assert.True(t, nanotime() <= nanotime(), "nanotime should never go backwards")
+
+	for _, q := range []WorkerArray{NewWorkerArray(StackType, 0), NewWorkerArray(LoopQueueType, 4)} {
+		stale := &goWorker{recycleTime: nanotime() - int64(time.Hour)}
+		fresh := &goWorker{recycleTime: nanotime()}
+		assert.NoError(t, q.Insert(stale), "Enqueue error")
+		assert.NoError(t, q.Insert(fresh), "Enqueue error")
+
+		expired := q.RetrieveExpiry(time.Minute)
+		assert.Equal(t, []*goWorker{stale}, expired, "only the stale worker should expire")
+		assert.EqualValues(t, 1, q.Len(), "Len error")
+	}
 }
@@ -16,21 +16,21 @@ func newWorkerStack(size int) *workerStack {
 	}
 }
 
-func (wq *workerStack) len() int {
+func (wq *workerStack) Len() int {
 	return len(wq.items)
 }
 
-func (wq *workerStack) isEmpty() bool {
+func (wq *workerStack) IsEmpty() bool {
 	return len(wq.items) == 0
 }
 
-func (wq *workerStack) insert(worker *goWorker) error {
+func (wq *workerStack) Insert(worker *goWorker) error {
 	wq.items = append(wq.items, worker)
 	return nil
 }
 
-func (wq *workerStack) detach() *goWorker {
-	l := wq.len()
+func (wq *workerStack) Detach() *goWorker {
+	l := wq.Len()
 	if l == 0 {
 		return nil
 	}
@@ -43,13 +43,13 @@ func (wq *workerStack) detach() *goWorker {
 }
 
 // 回收指定时间前的worker
-func (wq *workerStack) retrieveExpiry(duration time.Duration) []*goWorker {
-	n := wq.len()
+func (wq *workerStack) RetrieveExpiry(duration time.Duration) []*goWorker {
+	n := wq.Len()
 	if n == 0 {
 		return nil
 	}
 
-	expiryTime := time.Now().Add(-duration)
+	expiryTime := nanotime() - int64(duration)
 	// 找到过期的位置
 	index := wq.binarySearch(0, n-1, expiryTime)
 
@@ -69,12 +69,12 @@ func (wq *workerStack) retrieveExpiry(duration time.Duration) []*goWorker {
 }
 
 // 二分搜索
-func (wq *workerStack) binarySearch(l, r int, expiryTime time.Time) int {
+func (wq *workerStack) binarySearch(l, r int, expiryTime int64) int {
 	var mid int
 	for l <= r {
 		mid = (l + r) / 2
 		//
-		if expiryTime.Before(wq.items[mid].recycleTime) {
+		if expiryTime < wq.items[mid].recycleTime {
 			r = mid - 1
 		} else {
 			l = mid + 1
@@ -83,8 +83,14 @@ func (wq *workerStack) binarySearch(l, r int, expiryTime time.Time) int {
 	return r
 }
 
-func (wq *workerStack) reset() {
-	for i := 0; i < wq.len(); i++ {
+func (wq *workerStack) Each(fn func(w *goWorker)) {
+	for _, w := range wq.items {
+		fn(w)
+	}
+}
+
+func (wq *workerStack) Reset() {
+	for i := 0; i < wq.Len(); i++ {
 		wq.items[i].task <- nil
 		wq.items[i] = nil
 	}
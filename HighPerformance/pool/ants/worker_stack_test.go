@@ -12,25 +12,25 @@ import (
 func TestNewWorkerStack(t *testing.T) {
 	size := 100
 	q := newWorkerStack(size)
-	assert.EqualValues(t, 0, q.len(), "Len error")
-	assert.Equal(t, true, q.isEmpty(), "IsEmpty error")
-	assert.Nil(t, q.detach(), "Dequeue error")
+	assert.EqualValues(t, 0, q.Len(), "Len error")
+	assert.Equal(t, true, q.IsEmpty(), "IsEmpty error")
+	assert.Nil(t, q.Detach(), "Dequeue error")
 }
 
 func TestWorkerStack(t *testing.T) {
-	q := newWorkerArray(arrayType(-1), 0)
+	q := NewWorkerArray(ArrayType(-1), 0)
 
 	for i := 0; i < 5; i++ {
-		err := q.insert(&goWorker{recycleTime: time.Now()})
+		err := q.Insert(&goWorker{recycleTime: nanotime()})
 		if err != nil {
 			break
 		}
 	}
-	assert.EqualValues(t, 5, q.len(), "Len error")
+	assert.EqualValues(t, 5, q.Len(), "Len error")
 
-	expired := time.Now()
+	expired := nanotime()
 
-	err := q.insert(&goWorker{recycleTime: expired})
+	err := q.Insert(&goWorker{recycleTime: expired})
 	if err != nil {
 		t.Fatal("Enqueue error")
 	}
@@ -38,15 +38,15 @@ func TestWorkerStack(t *testing.T) {
 	time.Sleep(time.Second)
 
 	for i := 0; i < 6; i++ {
-		err := q.insert(&goWorker{recycleTime: time.Now()})
+		err := q.Insert(&goWorker{recycleTime: nanotime()})
 		if err != nil {
 			t.Fatal("Enqueue error")
 		}
 	}
-	assert.EqualValues(t, 12, q.len(), "Len error")
+	assert.EqualValues(t, 12, q.Len(), "Len error")
 	// 回收一分钟之前的worker
-	q.retrieveExpiry(time.Second)
-	assert.EqualValues(t, 6, q.len(), "Len error")
+	q.RetrieveExpiry(time.Second)
+	assert.EqualValues(t, 6, q.Len(), "Len error")
 }
 
 // It seems that something wrong with time.Now() on Windows, not sure whether it is a bug on Windows,
@@ -55,35 +55,35 @@ func TestSearch(t *testing.T) {
 	q := newWorkerStack(0)
 
 	// 1
-	expiry1 := time.Now()
+	expiry1 := nanotime()
 
-	_ = q.insert(&goWorker{recycleTime: time.Now()})
+	_ = q.Insert(&goWorker{recycleTime: nanotime()})
 
-	assert.EqualValues(t, 0, q.binarySearch(0, q.len()-1, time.Now()), "index should be 0")
-	assert.EqualValues(t, -1, q.binarySearch(0, q.len()-1, expiry1), "index should be -1")
+	assert.EqualValues(t, 0, q.binarySearch(0, q.Len()-1, nanotime()), "index should be 0")
+	assert.EqualValues(t, -1, q.binarySearch(0, q.Len()-1, expiry1), "index should be -1")
 
 	// 2
-	expiry2 := time.Now()
-	_ = q.insert(&goWorker{recycleTime: time.Now()})
+	expiry2 := nanotime()
+	_ = q.Insert(&goWorker{recycleTime: nanotime()})
 
-	assert.EqualValues(t, -1, q.binarySearch(0, q.len()-1, expiry1), "index should be -1")
+	assert.EqualValues(t, -1, q.binarySearch(0, q.Len()-1, expiry1), "index should be -1")
 
-	assert.EqualValues(t, 0, q.binarySearch(0, q.len()-1, expiry2), "index should be 0")
+	assert.EqualValues(t, 0, q.binarySearch(0, q.Len()-1, expiry2), "index should be 0")
 
-	assert.EqualValues(t, 1, q.binarySearch(0, q.len()-1, time.Now()), "index should be 1")
+	assert.EqualValues(t, 1, q.binarySearch(0, q.Len()-1, nanotime()), "index should be 1")
 
 	// more
 	for i := 0; i < 5; i++ {
-		_ = q.insert(&goWorker{recycleTime: time.Now()})
+		_ = q.Insert(&goWorker{recycleTime: nanotime()})
 	}
 
-	expiry3 := time.Now()
+	expiry3 := nanotime()
 
-	_ = q.insert(&goWorker{recycleTime: expiry3})
+	_ = q.Insert(&goWorker{recycleTime: expiry3})
 
 	for i := 0; i < 10; i++ {
-		_ = q.insert(&goWorker{recycleTime: time.Now()})
+		_ = q.Insert(&goWorker{recycleTime: nanotime()})
 	}
 
-	assert.EqualValues(t, 7, q.binarySearch(0, q.len()-1, expiry3), "index should be 7")
+	assert.EqualValues(t, 7, q.binarySearch(0, q.Len()-1, expiry3), "index should be 7")
 }